@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Rule identifiers used for annotations
+const (
+	RuleVersionMismatch = "version-mismatch"
+	RuleNotInReference  = "not-in-reference"
+	RuleNotInTarget     = "not-in-target"
+	RuleConstraint      = "constraint"
+	RuleGoVersion       = "go-version"
+	RuleToolchain       = "toolchain"
+	RuleGodebug         = "godebug"
+	RuleMissingTool     = "missing-tool"
+	RuleReplace         = "replace-mismatch"
+	RuleExcluded        = "excluded-version"
+	RuleTargetError     = "target-error"
+)
+
+// annotationRules describes every rule, in the order they are listed in SARIF output
+var annotationRules = []struct {
+	ID          string
+	Description string
+}{
+	{RuleVersionMismatch, "Dependency version differs from the reference"},
+	{RuleNotInReference, "Dependency is not required by the reference"},
+	{RuleNotInTarget, "Reference dependency is not required by the target"},
+	{RuleConstraint, "Dependency version violates a policy file constraint"},
+	{RuleGoVersion, "go directive differs from the reference"},
+	{RuleToolchain, "toolchain directive differs from the reference"},
+	{RuleGodebug, "godebug setting differs from the reference"},
+	{RuleMissingTool, "Reference tool is not declared by the target"},
+	{RuleReplace, "replace directive differs from the reference"},
+	{RuleExcluded, "Required version is excluded by the reference"},
+	{RuleTargetError, "Target go.mod file could not be checked"},
+}
+
+// Annotation is a single check finding located in a target go.mod file.
+// Line is 0 when the finding has no position in the file.
+type Annotation struct {
+	File    string
+	Line    int
+	RuleID  string
+	Message string
+}
+
+// modLines maps go.mod statements to the lines they are declared on
+type modLines struct {
+	module    int
+	goLine    int
+	toolchain int
+	requires  map[string]int
+	godebugs  map[string]int
+	replaces  map[string]int
+}
+
+// buildModLines indexes the statement positions of a parsed go.mod file
+func buildModLines(mod *modfile.File) modLines {
+	lines := modLines{
+		requires: make(map[string]int),
+		godebugs: make(map[string]int),
+		replaces: make(map[string]int),
+	}
+	if mod == nil {
+		return lines
+	}
+
+	if mod.Module != nil && mod.Module.Syntax != nil {
+		lines.module = mod.Module.Syntax.Start.Line
+	}
+	if mod.Go != nil && mod.Go.Syntax != nil {
+		lines.goLine = mod.Go.Syntax.Start.Line
+	}
+	if mod.Toolchain != nil && mod.Toolchain.Syntax != nil {
+		lines.toolchain = mod.Toolchain.Syntax.Start.Line
+	}
+	for _, req := range mod.Require {
+		if req.Syntax != nil {
+			lines.requires[req.Mod.Path] = req.Syntax.Start.Line
+		}
+	}
+	for _, godebug := range mod.Godebug {
+		if godebug.Syntax != nil {
+			lines.godebugs[godebug.Key] = godebug.Syntax.Start.Line
+		}
+	}
+	for _, rep := range mod.Replace {
+		if rep.Syntax != nil {
+			lines.replaces[rep.Old.String()] = rep.Syntax.Start.Line
+		}
+	}
+	return lines
+}
+
+// or returns line, or the module line when line is unknown
+func (l modLines) or(line int) int {
+	if line == 0 {
+		return l.module
+	}
+	return line
+}
+
+// CheckAnnotations returns one annotation per mismatch of a check report.
+// Each annotation points at the statement responsible for the mismatch;
+// findings with no statement in the target point at the module line.
+func CheckAnnotations(report *CheckReport) []Annotation {
+	var annotations []Annotation
+	for _, target := range report.Targets {
+		annotations = append(annotations, targetAnnotations(target)...)
+	}
+	return annotations
+}
+
+// annotationPath returns the path of a target as code scanning expects it:
+// relative to the repository root like the paths of a patch, or the cleaned
+// path for targets outside the repository and the working directory
+func annotationPath(target string) string {
+	if rel, err := patchPath(target); err == nil {
+		return rel
+	}
+	return filepath.ToSlash(filepath.Clean(target))
+}
+
+// targetAnnotations returns the annotations of a single checked target
+//
+//nolint:gocyclo // One case per kind of mismatch
+func targetAnnotations(target CheckTargetReport) []Annotation {
+	var annotations []Annotation
+	file := annotationPath(target.Target)
+	add := func(line int, ruleID, format string, args ...any) {
+		annotations = append(annotations, Annotation{File: file, Line: line, RuleID: ruleID, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if target.Error != "" {
+		add(0, RuleTargetError, "%s", target.Error)
+		return annotations
+	}
+
+	result := target.Result
+	lines := buildModLines(target.targetMod)
+
+	if m := result.GoVersionMismatch; m != nil {
+		add(lines.or(lines.goLine), RuleGoVersion, "go version %s does not match reference %s", formatOptional(m.TargetVersion), m.ReferenceVersion)
+	}
+	if m := result.ToolchainMismatch; m != nil {
+		add(lines.or(lines.toolchain), RuleToolchain, "toolchain %s does not match reference %s", formatOptional(m.TargetVersion), m.ReferenceVersion)
+	}
+	for _, m := range result.GodebugMismatches {
+		add(lines.or(lines.godebugs[m.Key]), RuleGodebug, "godebug %s=%s does not match reference %s", m.Key, formatOptional(m.TargetValue), m.ReferenceValue)
+	}
+	for _, tool := range result.MissingTools {
+		add(lines.module, RuleMissingTool, "tool %s is declared by the reference but not by the target", tool)
+	}
+	for _, m := range result.DependencyMismatches {
+		switch {
+		case m.OnlyInTarget:
+			add(lines.or(lines.requires[m.Module]), RuleNotInReference, "%s %s is not required by the reference", m.Module, m.TargetVersion)
+		case m.OnlyInReference:
+			add(lines.module, RuleNotInTarget, "%s %s is required by the reference but not by the target", m.Module, m.ReferenceVersion)
+		case m.Constraint == string(ConstraintDeny):
+			add(lines.or(lines.requires[m.Module]), RuleConstraint, "%s %s is denied by the policy file", m.Module, m.TargetVersion)
+		case m.Constraint != "":
+			add(lines.or(lines.requires[m.Module]), RuleConstraint, "%s %s does not satisfy %q", m.Module, m.TargetVersion, m.Constraint)
+		default:
+			add(lines.or(lines.requires[m.Module]), RuleVersionMismatch, "%s %s does not match reference %s", m.Module, m.TargetVersion, m.ReferenceVersion)
+		}
+	}
+	for _, m := range result.ReplaceMismatches {
+		replaced := formatReplaced(m.Module, m.ModuleVersion)
+		line := lines.replaces[replaced]
+		if line == 0 {
+			line = lines.requires[m.Module]
+		}
+		add(lines.or(line), RuleReplace, "replace %s => %s does not match reference %s", replaced,
+			formatReplacement(m.TargetReplacement, m.TargetReplacementVersion),
+			formatReplacement(m.ReferenceReplacement, m.ReferenceReplacementVersion))
+	}
+	for _, e := range result.ExcludedRequirements {
+		add(lines.or(lines.requires[e.Module]), RuleExcluded, "%s %s is excluded by the reference", e.Module, e.Version)
+	}
+
+	return annotations
+}
+
+// writeGitHubAnnotations writes the annotations as GitHub Actions workflow commands
+func writeGitHubAnnotations(w io.Writer, annotations []Annotation) error {
+	for _, a := range annotations {
+		props := "file=" + escapeGitHubProperty(a.File)
+		if a.Line > 0 {
+			props += fmt.Sprintf(",line=%d", a.Line)
+		}
+		if _, err := fmt.Fprintf(w, "::error %s::%s\n", props, escapeGitHubData(a.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGitHubData escapes the message of a workflow command
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
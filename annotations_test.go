@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const annotationTargetContent = `module example.com/test
+
+go 1.21
+
+toolchain go1.21.0
+
+godebug panicnil=1
+
+require (
+	golang.org/x/text v0.3.0
+	github.com/pkg/errors v0.9.1
+)
+
+require github.com/extra/dep v1.0.0 // indirect
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.2
+`
+
+const annotationReferenceContent = `module example.com/reference
+
+go 1.22
+
+toolchain go1.22.0
+
+godebug panicnil=0
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.4.0
+	github.com/google/uuid v1.6.0
+)
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3
+`
+
+func TestCheckAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", annotationTargetContent)
+	missing := filepath.Join(dir, "missing", "go.mod")
+
+	referenceMod, err := createTestModFile(annotationReferenceContent)
+	require.NoError(t, err)
+
+	opts := CheckOptions{
+		Strict:        true,
+		ReverseStrict: true,
+		Replaces:      true,
+		Directives:    DirectiveSet{DirectiveGo: true, DirectiveToolchain: true, DirectiveGodebug: true},
+	}
+	report := RunCheck([]string{target, missing}, "ref/go.mod", referenceMod, opts)
+
+	file := filepath.ToSlash(target)
+	expected := []Annotation{
+		{File: file, Line: 3, RuleID: RuleGoVersion, Message: "go version 1.21 does not match reference 1.22"},
+		{File: file, Line: 5, RuleID: RuleToolchain, Message: "toolchain go1.21.0 does not match reference go1.22.0"},
+		{File: file, Line: 7, RuleID: RuleGodebug, Message: "godebug panicnil=1 does not match reference 0"},
+		{File: file, Line: 14, RuleID: RuleNotInReference, Message: "github.com/extra/dep v1.0.0 is not required by the reference"},
+		{File: file, Line: 1, RuleID: RuleNotInTarget, Message: "github.com/google/uuid v1.6.0 is required by the reference but not by the target"},
+		{File: file, Line: 10, RuleID: RuleVersionMismatch, Message: "golang.org/x/text v0.3.0 does not match reference v0.4.0"},
+		{
+			File: file, Line: 16, RuleID: RuleReplace,
+			Message: "replace github.com/pkg/errors => github.com/fork/errors v0.9.2 does not match reference github.com/fork/errors v0.9.3",
+		},
+	}
+
+	annotations := CheckAnnotations(report)
+	require.Len(t, annotations, len(expected)+1)
+	assert.Equal(t, expected, annotations[:len(expected)])
+
+	last := annotations[len(expected)]
+	assert.Equal(t, filepath.ToSlash(missing), last.File)
+	assert.Equal(t, 0, last.Line)
+	assert.Equal(t, RuleTargetError, last.RuleID)
+}
+
+func TestAnnotationPath(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o750))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "services", "api"), 0o750))
+	outside := filepath.Join(t.TempDir(), "go.mod")
+
+	tests := []struct {
+		name     string
+		dir      string
+		target   string
+		expected string
+	}{
+		{"relative to the root", ".", "./services/api/go.mod", "services/api/go.mod"},
+		{"relative to a subdirectory", "services", "api/../api/go.mod", "services/api/go.mod"},
+		{"parent of a subdirectory", "services/api", "../../go.mod", "go.mod"},
+		{"absolute", ".", filepath.Join(root, "services", "api", "go.mod"), "services/api/go.mod"},
+		{"outside the repository", ".", outside, filepath.ToSlash(outside)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(filepath.Join(root, tt.dir))
+			assert.Equal(t, tt.expected, annotationPath(tt.target))
+		})
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeGitHubAnnotations(&buf, []Annotation{
+		{File: "services/api/go.mod", Line: 12, RuleID: RuleVersionMismatch, Message: "golang.org/x/text v0.3.0 does not match reference v0.4.0"},
+		{File: "a,b:c/go.mod", RuleID: RuleTargetError, Message: "100% broken\nsecond line"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"::error file=services/api/go.mod,line=12::golang.org/x/text v0.3.0 does not match reference v0.4.0\n"+
+			"::error file=a%2Cb%3Ac/go.mod::100%25 broken%0Asecond line\n",
+		buf.String())
+}
+
+func TestBuildSARIF(t *testing.T) {
+	log := buildSARIF([]Annotation{
+		{File: "go.mod", Line: 12, RuleID: RuleVersionMismatch, Message: "golang.org/x/text v0.3.0 does not match reference v0.4.0"},
+		{File: "other/go.mod", RuleID: RuleTargetError, Message: "failed to read target file"},
+	})
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "gomodsync", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, len(annotationRules))
+
+	require.Len(t, run.Results, 2)
+	assert.Equal(t, RuleVersionMismatch, run.Results[0].RuleID)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, "go.mod", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.NotNil(t, run.Results[0].Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, 12, run.Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Nil(t, run.Results[1].Locations[0].PhysicalLocation.Region)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSARIF(&buf, nil))
+	assert.Contains(t, buf.String(), `"results": []`)
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// tokenEnv names the environment variable holding a bearer token for downloads
+const tokenEnv = "GOMODSYNC_TOKEN"
+
+// headerList is a repeatable flag collecting "Name: value" request headers
+type headerList struct {
+	header *http.Header
+}
+
+func (l headerList) String() string {
+	if l.header == nil {
+		return ""
+	}
+	// Header values may hold credentials, so only the names are shown
+	names := make([]string, 0, len(*l.header))
+	for name := range *l.header {
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (l headerList) Set(value string) error {
+	name, headerValue, found := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("expected \"Name: value\"")
+	}
+	if *l.header == nil {
+		*l.header = make(http.Header)
+	}
+	l.header.Add(name, strings.TrimSpace(headerValue))
+	return nil
+}
+
+// credentialHosts returns the hosts of the reference and policy file URLs,
+// the only hosts -auth-header and GOMODSYNC_TOKEN are sent to
+func credentialHosts(locations []string) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, location := range locations {
+		if !isURL(location) {
+			continue
+		}
+		if u, err := neturl.Parse(location); err == nil {
+			hosts[u.Host] = true
+		}
+	}
+	return hosts
+}
+
+// requestHeaders returns the authentication headers for a download. Headers
+// from -auth-header come first; without an Authorization header among them,
+// GOMODSYNC_TOKEN is sent as a bearer token, and without a token the GOAUTH
+// credential providers are asked. -auth-header and GOMODSYNC_TOKEN only go to
+// the hosts in settings.AuthHosts. No credentials are sent over plain HTTP
+// unless settings.InsecureAuth is set.
+func requestHeaders(settings FetchSettings, url string) (http.Header, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	secure := u.Scheme == "https" || settings.InsecureAuth
+	header := make(http.Header)
+	token := os.Getenv(tokenEnv)
+	if settings.AuthHosts[u.Host] && (len(settings.Headers) > 0 || token != "") {
+		if !secure {
+			return nil, fmt.Errorf("refusing to send -auth-header or %s to %s over plain HTTP (use https or -allow-insecure-auth)", tokenEnv, u.Host)
+		}
+		header = settings.Headers.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		if header.Get("Authorization") == "" && token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	// Like the go command, only ask the providers for HTTPS URLs
+	if header.Get("Authorization") != "" || !secure {
+		return header, nil
+	}
+
+	credentials, err := goAuthHeaders(settings.Context, url)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range credentials {
+		if header.Get(name) == "" {
+			header[name] = values
+		}
+	}
+	return header, nil
+}
+
+// goAuthHeaders asks the credential providers listed in GOAUTH for the headers
+// of a URL, in the format the go command uses: providers are separated by
+// semicolons and are "off", "netrc", "git <dir>" or a command. The first
+// provider with credentials wins. GOAUTH defaults to netrc.
+func goAuthHeaders(ctx context.Context, url string) (http.Header, error) {
+	value := os.Getenv("GOAUTH")
+	if value == "" {
+		value = "netrc"
+	}
+
+	for _, provider := range strings.Split(value, ";") {
+		fields := strings.Fields(provider)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var header http.Header
+		var err error
+		switch fields[0] {
+		case "off":
+			return nil, nil
+		case "netrc":
+			header, err = netrcHeaders(url)
+		case "git":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid GOAUTH entry %q: expected git <dir>", provider)
+			}
+			header, err = gitCredentialHeaders(ctx, fields[1], url)
+		default:
+			header, err = commandHeaders(ctx, fields, url)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GOAUTH %s: %w", fields[0], err)
+		}
+		if len(header) > 0 {
+			return header, nil
+		}
+	}
+	return nil, nil
+}
+
+// basicAuthHeader returns an Authorization header for a user name and password
+func basicAuthHeader(login, password string) http.Header {
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(login, password)
+	return req.Header
+}
+
+// netrcLine is one machine entry of a .netrc file
+type netrcLine struct {
+	machine  string
+	login    string
+	password string
+}
+
+// parseNetrc parses the machine entries of a .netrc file. Tokens may span
+// lines, and macro definitions are skipped. Like the go command, it ignores the
+// default entry, which would send its credentials to every host, and stops
+// there since default must be the last entry.
+func parseNetrc(data string) []netrcLine {
+	var lines []netrcLine
+	var current *netrcLine
+	var keyword string
+	inMacro := false
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		text := scanner.Text()
+		// A macro definition runs until the next empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(text) != ""
+			continue
+		}
+
+		for _, field := range strings.Fields(text) {
+			// The value of the previous keyword
+			if keyword != "" {
+				switch keyword {
+				case "machine":
+					current.machine = field
+				case "login":
+					current.login = field
+				case "password":
+					current.password = field
+				}
+				keyword = ""
+				continue
+			}
+
+			switch field {
+			case "default":
+				return lines
+			case "machine":
+				lines = append(lines, netrcLine{})
+				current = &lines[len(lines)-1]
+				keyword = field
+			case "login", "password", "account":
+				if current != nil {
+					keyword = field
+				}
+			case "macdef":
+				inMacro = true
+			}
+			if inMacro {
+				break
+			}
+		}
+	}
+	return lines
+}
+
+// netrcPath returns the location of the .netrc file, honouring $NETRC
+func netrcPath() (string, error) {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name), nil
+}
+
+// netrcHeaders returns basic authentication for the URL's host from the
+// .netrc file. A missing file yields none.
+func netrcHeaders(url string) (http.Header, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	path, err := netrcPath()
+	if err != nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- the netrc location is chosen by the user
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, line := range parseNetrc(string(data)) {
+		if line.machine == u.Hostname() {
+			return basicAuthHeader(line.login, line.password), nil
+		}
+	}
+	return nil, nil
+}
+
+// gitCredentialHeaders asks `git credential fill`, run in dir, for basic
+// authentication for the URL
+func gitCredentialHeaders(ctx context.Context, dir, url string) (http.Header, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"))
+
+	// #nosec G204 -- the directory comes from the user's GOAUTH setting
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git credential fill: %w", err)
+	}
+
+	var login, password string
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			login = value
+		case "password":
+			password = value
+		}
+	}
+	if password == "" {
+		return nil, nil
+	}
+	return basicAuthHeader(login, password), nil
+}
+
+// commandHeaders runs a GOAUTH credential command with the URL as its last
+// argument. Its output is a series of blocks, each listing URL prefixes, a
+// blank line, HTTP headers and another blank line; the headers of the first
+// block with a prefix of the URL are used.
+func commandHeaders(ctx context.Context, command []string, url string) (http.Header, error) {
+	// #nosec G204 -- the command comes from the user's GOAUTH setting
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:], url)...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(out)))
+	for {
+		var prefixes []string
+		for {
+			line, err := reader.ReadLine()
+			if err != nil {
+				return nil, nil
+			}
+			if line == "" {
+				break
+			}
+			prefixes = append(prefixes, line)
+		}
+		if len(prefixes) == 0 {
+			return nil, nil
+		}
+
+		header, err := reader.ReadMIMEHeader()
+		if err != nil && len(header) == 0 {
+			return nil, fmt.Errorf("invalid output: %w", err)
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(url, prefix) {
+				return http.Header(header), nil
+			}
+		}
+	}
+}
+
+// Credentials in a reference are replaced by this placeholder for display
+const redacted = "xxxxx"
+
+var (
+	// userinfoPattern matches the user information of a URL, e.g. user:token@
+	userinfoPattern = regexp.MustCompile(`://[^/?#@]+@`)
+	// secretQueryPattern matches query parameters that commonly carry credentials
+	secretQueryPattern = regexp.MustCompile(`(?i)([?&][a-z_-]*(?:token|key|secret|password|signature|sig)=)[^&#]*`)
+)
+
+// redactReference hides credentials embedded in a reference: the user
+// information of URLs and query parameters such as ?token=
+func redactReference(reference string) string {
+	reference = userinfoPattern.ReplaceAllString(reference, "://"+redacted+"@")
+	return secretQueryPattern.ReplaceAllString(reference, "${1}"+redacted)
+}
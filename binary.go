@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"fmt"
+	gover "go/version"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// binaryPrefix marks a reference read from the build info of a Go binary
+const binaryPrefix = "bin:"
+
+// isBinaryReference checks if the reference names a compiled Go binary
+func isBinaryReference(reference string) bool {
+	return strings.HasPrefix(reference, binaryPrefix)
+}
+
+// BuildBinaryReference converts the build info embedded in a Go binary into a
+// reference modfile. Every module linked into the binary becomes a requirement
+// at the version it was built with, and replaced modules also get a replace
+// directive. The go directive is the Go version that built the binary; it is
+// left out for development toolchains.
+func BuildBinaryReference(filename string, info *debug.BuildInfo) (*modfile.File, error) {
+	reference, err := ParseGoMod(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Experiments are listed after the version, e.g. "go1.22.3 X:boringcrypto"
+	if fields := strings.Fields(info.GoVersion); len(fields) > 0 && gover.IsValid(fields[0]) {
+		if err := reference.AddGoStmt(strings.TrimPrefix(fields[0], "go")); err != nil {
+			return nil, fmt.Errorf("failed to set Go version: %w", err)
+		}
+	}
+
+	for _, dep := range info.Deps {
+		reference.AddNewRequire(dep.Path, dep.Version, false)
+		if dep.Replace != nil {
+			if err := reference.AddReplace(dep.Path, "", dep.Replace.Path, dep.Replace.Version); err != nil {
+				return nil, fmt.Errorf("failed to add replace %s: %w", dep.Path, err)
+			}
+		}
+	}
+
+	return reference, nil
+}
+
+// loadBinaryReference fetches a Go binary (from URL or local path) and builds
+// a reference modfile from its build info
+func loadBinaryReference(reference string) (*modfile.File, error) {
+	location := strings.TrimPrefix(reference, binaryPrefix)
+	data, err := FetchReference(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reference: %w", err)
+	}
+
+	info, err := buildinfo.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build info of %s: %w", location, err)
+	}
+
+	return BuildBinaryReference(GetReferenceDisplayName(reference), info)
+}
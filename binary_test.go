@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildBinaryReference(t *testing.T) {
+	tests := []struct {
+		name       string
+		goVersion  string
+		expectedGo string
+		expectNoGo bool
+	}{
+		{"release", "go1.22.3", "1.22.3", false},
+		{"experiment", "go1.22.3 X:boringcrypto", "1.22.3", false},
+		{"release candidate", "go1.23rc1", "1.23rc1", false},
+		{"development toolchain", "devel go1.24-abcdef Mon Jan 1 00:00:00 2024 +0000", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &debug.BuildInfo{
+				GoVersion: tt.goVersion,
+				Path:      "example.com/api/cmd/server",
+				Main:      debug.Module{Path: "example.com/api", Version: "(devel)"},
+				Deps: []*debug.Module{
+					{Path: "github.com/pkg/errors", Version: "v0.9.1"},
+					{Path: "golang.org/x/text", Version: "v0.14.0", Replace: &debug.Module{Path: "example.com/fork/text", Version: "v0.14.1"}},
+					{Path: "example.com/internal", Version: "v1.0.0", Replace: &debug.Module{Path: "../internal"}},
+				},
+			}
+
+			reference, err := BuildBinaryReference("bin:server", info)
+			require.NoError(t, err)
+
+			if tt.expectNoGo {
+				assert.Nil(t, reference.Go)
+			} else {
+				require.NotNil(t, reference.Go)
+				assert.Equal(t, tt.expectedGo, reference.Go.Version)
+			}
+
+			assert.Equal(t, VersionMap{
+				"github.com/pkg/errors": "v0.9.1",
+				"golang.org/x/text":     "v0.14.0",
+				"example.com/internal":  "v1.0.0",
+			}, BuildVersionMap(reference))
+
+			replaces := BuildReplaceMap(reference)
+			require.Len(t, replaces, 2)
+			assert.Equal(t, "example.com/fork/text", replaces["golang.org/x/text"].New.Path)
+			assert.Equal(t, "v0.14.1", replaces["golang.org/x/text"].New.Version)
+			assert.Equal(t, "../internal", replaces["example.com/internal"].New.Path)
+		})
+	}
+}
+
+func TestLoadReference_Binary(t *testing.T) {
+	// The test binary itself carries build info for this module's dependencies
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	info, ok := debug.ReadBuildInfo()
+	require.True(t, ok)
+	require.NotEmpty(t, info.Deps)
+
+	reference, err := loadReference(binaryPrefix + executable)
+	require.NoError(t, err)
+
+	versions := BuildVersionMap(reference)
+	for _, dep := range info.Deps {
+		assert.Equal(t, dep.Version, versions[dep.Path], dep.Path)
+	}
+	require.NotNil(t, reference.Go)
+	assert.True(t, strings.HasPrefix(info.GoVersion, "go"+reference.Go.Version))
+}
+
+func TestLoadReference_BinaryErrors(t *testing.T) {
+	dir := t.TempDir()
+	notBinary := filepath.Join(dir, "server")
+	require.NoError(t, os.WriteFile(notBinary, []byte("#!/bin/sh\necho hello\n"), 0o600))
+
+	_, err := loadReference(binaryPrefix + notBinary)
+	assert.ErrorContains(t, err, "build info")
+
+	_, err = loadReference(binaryPrefix + filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gover "go/version"
+	"io"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// Supported build list formats
+const (
+	BuildListGoList   = "go list -m -json"
+	BuildListModGraph = "go mod graph"
+)
+
+// goListModule is one module of `go list -m -json all` output
+type goListModule struct {
+	Path      string
+	Version   string
+	Main      bool
+	Indirect  bool
+	GoVersion string
+	Replace   *goListModule
+}
+
+// DetectBuildListFormat returns the format of a build list dump, or "" if
+// data is neither `go list -m -json all` nor `go mod graph` output
+func DetectBuildListFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return ""
+	}
+
+	if trimmed[0] == '{' {
+		var first goListModule
+		if err := json.NewDecoder(bytes.NewReader(trimmed)).Decode(&first); err != nil || first.Path == "" {
+			return ""
+		}
+		return BuildListGoList
+	}
+
+	// Every line of a module graph is an edge "from to@version"
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.Contains(fields[1], "@") {
+			return ""
+		}
+	}
+	return BuildListModGraph
+}
+
+// BuildGoListReference converts `go list -m -json all` output into a reference
+// modfile requiring every module of the build list at its selected version.
+// Replaced modules also get a replace directive, and the go directive is the
+// highest go version of the main modules.
+func BuildGoListReference(filename string, data []byte) (*modfile.File, error) {
+	reference, err := ParseGoMod(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var goVersion string
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var mod goListModule
+		if err := decoder.Decode(&mod); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid go list output: %w", err)
+		}
+
+		if mod.Main {
+			if mod.GoVersion != "" && gover.Compare("go"+mod.GoVersion, "go"+goVersion) > 0 {
+				goVersion = mod.GoVersion
+			}
+			continue
+		}
+		// Modules that failed to load have no version
+		if mod.Version == "" {
+			continue
+		}
+
+		reference.AddNewRequire(mod.Path, mod.Version, mod.Indirect)
+		if mod.Replace != nil {
+			if err := reference.AddReplace(mod.Path, "", mod.Replace.Path, mod.Replace.Version); err != nil {
+				return nil, fmt.Errorf("failed to add replace %s: %w", mod.Path, err)
+			}
+		}
+	}
+
+	if goVersion != "" {
+		if err := reference.AddGoStmt(goVersion); err != nil {
+			return nil, fmt.Errorf("failed to set Go version: %w", err)
+		}
+	}
+	return reference, nil
+}
+
+// BuildModGraphReference converts `go mod graph` output into a reference
+// modfile. Each module is required at the highest version in the graph, which
+// is the version minimal version selection picks. Modules the main module
+// requires are direct and all others indirect. The go and toolchain
+// directives come from the main module's go@ and toolchain@ edges.
+func BuildModGraphReference(filename string, data []byte) (*modfile.File, error) {
+	reference, err := ParseGoMod(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &modGraph{versions: make(VersionMap), indirect: make(map[string]bool)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if err := graph.addEdge(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go mod graph: %w", err)
+	}
+
+	if graph.goVersion != "" {
+		if err := reference.AddGoStmt(graph.goVersion); err != nil {
+			return nil, fmt.Errorf("failed to set Go version: %w", err)
+		}
+	}
+	if graph.toolchain != "" {
+		if err := reference.AddToolchainStmt(graph.toolchain); err != nil {
+			return nil, fmt.Errorf("failed to set toolchain: %w", err)
+		}
+	}
+
+	addRequires(reference, graph.versions, graph.indirect)
+	return reference, nil
+}
+
+// modGraph collects the build list of `go mod graph` output
+type modGraph struct {
+	versions  VersionMap      // highest version of each module
+	indirect  map[string]bool // modules the main module does not require
+	goVersion string          // go version of the main module
+	toolchain string          // toolchain of the main module
+}
+
+// addEdge records a line of `go mod graph` output. Blank lines are ignored.
+func (g *modGraph) addEdge(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid go mod graph line %q", line)
+	}
+
+	// The main module is the only one listed without a version
+	fromMain := !strings.Contains(fields[0], "@")
+	module, version, _ := strings.Cut(fields[1], "@")
+	switch module {
+	case "go":
+		if fromMain {
+			g.goVersion = version
+		}
+		return nil
+	case "toolchain":
+		if fromMain {
+			g.toolchain = version
+		}
+		return nil
+	}
+
+	if !semver.IsValid(version) {
+		return fmt.Errorf("invalid version in go mod graph line %q", line)
+	}
+	if current, exists := g.versions[module]; !exists || semver.Compare(version, current) > 0 {
+		g.versions[module] = version
+	}
+	// A module is direct once the main module requires it
+	if _, seen := g.indirect[module]; !seen || fromMain {
+		g.indirect[module] = !fromMain
+	}
+	return nil
+}
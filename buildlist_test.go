@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const goListTestOutput = `{
+	"Path": "example.com/api",
+	"Main": true,
+	"Dir": "/src/api",
+	"GoMod": "/src/api/go.mod",
+	"GoVersion": "1.22.1"
+}
+{
+	"Path": "github.com/davecgh/go-spew",
+	"Version": "v1.1.1",
+	"Indirect": true,
+	"GoMod": "/go/pkg/mod/cache/download/github.com/davecgh/go-spew/@v/v1.1.1.mod"
+}
+{
+	"Path": "github.com/stretchr/testify",
+	"Version": "v1.9.0",
+	"GoVersion": "1.17"
+}
+{
+	"Path": "golang.org/x/text",
+	"Version": "v0.14.0",
+	"Replace": {
+		"Path": "example.com/fork/text",
+		"Version": "v0.14.1"
+	}
+}
+{
+	"Path": "example.com/broken",
+	"Error": {
+		"Err": "module example.com/broken: not found"
+	}
+}
+`
+
+const modGraphTestOutput = `example.com/api github.com/stretchr/testify@v1.9.0
+example.com/api go@1.22.1
+example.com/api golang.org/x/text@v0.13.0
+example.com/api toolchain@go1.22.3
+github.com/stretchr/testify@v1.9.0 github.com/davecgh/go-spew@v1.1.1
+github.com/stretchr/testify@v1.9.0 golang.org/x/text@v0.14.0
+github.com/stretchr/testify@v1.9.0 go@1.17
+go@1.22.1 toolchain@go1.22.1
+`
+
+func TestDetectBuildListFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"go list", goListTestOutput, BuildListGoList},
+		{"go mod graph", modGraphTestOutput, BuildListModGraph},
+		{"go.mod", "module example.com/api\n\ngo 1.22\n\nrequire golang.org/x/text v0.14.0\n", ""},
+		{"go.mod with only module", "module example.com/api\n", ""},
+		{"SBOM", cycloneDXTestSBOM, ""},
+		{"empty", "\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectBuildListFormat([]byte(tt.input)))
+		})
+	}
+}
+
+func TestBuildGoListReference(t *testing.T) {
+	reference, err := BuildGoListReference("build.json", []byte(goListTestOutput))
+	require.NoError(t, err)
+
+	assert.Equal(t, VersionMap{
+		"github.com/davecgh/go-spew":  "v1.1.1",
+		"github.com/stretchr/testify": "v1.9.0",
+		"golang.org/x/text":           "v0.14.0",
+	}, BuildVersionMap(reference))
+
+	indirect := make(map[string]bool)
+	for _, req := range reference.Require {
+		indirect[req.Mod.Path] = req.Indirect
+	}
+	assert.True(t, indirect["github.com/davecgh/go-spew"])
+	assert.False(t, indirect["github.com/stretchr/testify"])
+
+	require.NotNil(t, reference.Go)
+	assert.Equal(t, "1.22.1", reference.Go.Version)
+
+	replaces := BuildReplaceMap(reference)
+	require.Contains(t, replaces, "golang.org/x/text")
+	assert.Equal(t, "example.com/fork/text", replaces["golang.org/x/text"].New.Path)
+
+	_, err = BuildGoListReference("build.json", []byte(`{"Path": "example.com/api", "Main": true}{"Path": `))
+	assert.Error(t, err)
+}
+
+func TestBuildModGraphReference(t *testing.T) {
+	reference, err := BuildModGraphReference("graph.txt", []byte(modGraphTestOutput))
+	require.NoError(t, err)
+
+	// golang.org/x/text is selected at the higher version required by testify
+	assert.Equal(t, VersionMap{
+		"github.com/davecgh/go-spew":  "v1.1.1",
+		"github.com/stretchr/testify": "v1.9.0",
+		"golang.org/x/text":           "v0.14.0",
+	}, BuildVersionMap(reference))
+
+	indirect := make(map[string]bool)
+	for _, req := range reference.Require {
+		indirect[req.Mod.Path] = req.Indirect
+	}
+	assert.True(t, indirect["github.com/davecgh/go-spew"])
+	assert.False(t, indirect["golang.org/x/text"])
+
+	require.NotNil(t, reference.Go)
+	assert.Equal(t, "1.22.1", reference.Go.Version)
+	require.NotNil(t, reference.Toolchain)
+	assert.Equal(t, "go1.22.3", reference.Toolchain.Name)
+
+	_, err = BuildModGraphReference("graph.txt", []byte("example.com/api golang.org/x/text@latest\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadReference_BuildList(t *testing.T) {
+	dir := t.TempDir()
+	targetMod, err := createTestModFile("module example.com/api\n\ngo 1.22.1\n\nrequire (\n\tgithub.com/stretchr/testify v1.9.0\n\tgolang.org/x/text v0.13.0\n)\n")
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{"build.json": goListTestOutput, "graph.txt": modGraphTestOutput} {
+		t.Run(name, func(t *testing.T) {
+			referenceMod, err := loadReference(writeTestFile(t, dir, name, content))
+			require.NoError(t, err)
+
+			// The target is held to the version selected through a transitive requirement
+			result := CheckVersions(targetMod, referenceMod, false)
+			require.Len(t, result.DependencyMismatches, 1)
+			assert.Equal(t, "golang.org/x/text", result.DependencyMismatches[0].Module)
+			assert.Equal(t, "v0.14.0", result.DependencyMismatches[0].ReferenceVersion)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry is a downloaded response stored with the validators needed to
+// revalidate it through a conditional request
+type cacheEntry struct {
+	URL          string // with credentials redacted, as the file name already identifies it
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+	Data         []byte
+}
+
+// errNotCached reports an -offline download missing from the cache
+var errNotCached = errors.New("not in the download cache (running with -offline)")
+
+// defaultCacheDir returns the download cache under the user cache directory,
+// or "" if there is none
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gomodsync", "http")
+}
+
+// cachePath returns the file of a URL's cache entry
+func cachePath(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadCacheEntry returns the cached response of a URL, or nil if caching is
+// disabled or the URL was never stored. Unreadable entries count as missing.
+func loadCacheEntry(dir, url string) *cacheEntry {
+	if dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cachePath(dir, url))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != redactReference(url) {
+		return nil
+	}
+	return &entry
+}
+
+// storeCacheEntry saves the response of a URL for later revalidation. The
+// entry records the URL without its credentials, and is written to a temporary
+// file first so readers never see a partial one.
+func storeCacheEntry(dir, url string, entry *cacheEntry) error {
+	if dir == "" {
+		return nil
+	}
+	entry.URL = redactReference(url)
+	// Cached references may come from private servers
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath(dir, url))
+}
+
+// addValidators makes a request conditional on the cached entry having changed
+func addValidators(header http.Header, entry *cacheEntry) {
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
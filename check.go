@@ -3,29 +3,75 @@ package main
 import "golang.org/x/mod/modfile"
 
 // CheckVersions compares versions between target and reference
-// and returns mismatches. If strict is true, it also reports
-// dependencies that exist only in target.
+// and returns mismatches, including requirements on versions the reference
+// excludes. If strict is true, it also reports dependencies that exist
+// only in target.
 func CheckVersions(targetMod, referenceMod *modfile.File, strict bool) *CheckResult {
-	result := &CheckResult{}
+	return CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Strict: strict, Directives: DefaultDirectives(), Excludes: true})
+}
+
+// CheckVersionsWithOptions compares dependency versions between target and
+// reference like CheckVersions, together with the directives selected in opts
+func CheckVersionsWithOptions(targetMod, referenceMod *modfile.File, opts CheckOptions) *CheckResult {
+	result := &CheckResult{
+		DependencyMismatches: checkRequirements(targetMod, referenceMod, opts),
+	}
+	checkDirectives(targetMod, referenceMod, opts, result)
+
+	// Check replace directives
+	if opts.Replaces {
+		for _, change := range CompareReplaces(targetMod, referenceMod, opts.LocalReplaces) {
+			result.ReplaceMismatches = append(result.ReplaceMismatches, ReplaceMismatch{
+				Module:                      change.Module,
+				ModuleVersion:               change.ModuleVersion,
+				TargetReplacement:           change.OldReplacement,
+				TargetReplacementVersion:    change.OldReplacementVersion,
+				ReferenceReplacement:        change.NewReplacement,
+				ReferenceReplacementVersion: change.NewReplacementVersion,
+			})
+		}
+	}
+
+	// Check requirements against the reference excludes
+	if opts.Excludes {
+		result.ExcludedRequirements = FindExcludedRequirements(targetMod, referenceMod)
+	}
+
+	opts.Sources.annotateCheckResult(result)
+	return result
+}
 
+// checkRequirements compares the target's requirements with the reference and
+// the policy file constraints, reporting the modules found only on one side
+// when opts ask for it
+func checkRequirements(targetMod, referenceMod *modfile.File, opts CheckOptions) []VersionMismatch {
+	var mismatches []VersionMismatch
 	refVersions := BuildVersionMap(referenceMod)
 	targetVersions := BuildVersionMap(targetMod)
 
 	// Check for version mismatches and missing in reference
 	for module, targetVersion := range targetVersions {
+		// Modules with a policy file constraint are checked against it instead
+		if constraint, ok := opts.Constraints.Lookup(module); ok {
+			if mismatch := checkConstraint(module, targetVersion, constraint, opts.Constraints.candidates(module, refVersions, opts.Sources), opts.Constraints.Prefer); mismatch != nil {
+				mismatches = append(mismatches, *mismatch)
+			}
+			continue
+		}
+
 		if refVersion, exists := refVersions[module]; exists {
-			// Module exists in both, check version
-			if targetVersion != refVersion {
-				result.DependencyMismatches = append(result.DependencyMismatches, VersionMismatch{
+			// Module exists in both, check version under the policy
+			if opts.Policy.ShouldReport(targetVersion, refVersion) {
+				mismatches = append(mismatches, VersionMismatch{
 					Module:           module,
 					TargetVersion:    targetVersion,
 					ReferenceVersion: refVersion,
 					OnlyInTarget:     false,
 				})
 			}
-		} else if strict {
+		} else if opts.Strict {
 			// Module only exists in target, report if strict mode
-			result.DependencyMismatches = append(result.DependencyMismatches, VersionMismatch{
+			mismatches = append(mismatches, VersionMismatch{
 				Module:           module,
 				TargetVersion:    targetVersion,
 				ReferenceVersion: "",
@@ -34,7 +80,64 @@ func CheckVersions(targetMod, referenceMod *modfile.File, strict bool) *CheckRes
 		}
 	}
 
+	// Check for dependencies missing from target
+	if opts.ReverseStrict {
+		for _, req := range referenceMod.Require {
+			if opts.Constraints.governs(req.Mod.Path, ConstraintDeny, ConstraintIgnore) {
+				continue
+			}
+			if _, exists := targetVersions[req.Mod.Path]; !exists {
+				mismatches = append(mismatches, VersionMismatch{
+					Module:           req.Mod.Path,
+					TargetVersion:    "",
+					ReferenceVersion: req.Mod.Version,
+					OnlyInReference:  true,
+				})
+			}
+		}
+	}
+	return mismatches
+}
+
+// checkDirectives compares the go, toolchain, godebug and tool directives
+// selected in opts
+func checkDirectives(targetMod, referenceMod *modfile.File, opts CheckOptions, result *CheckResult) {
 	// Check Go version
+	if opts.Directives[DirectiveGo] {
+		result.GoVersionMismatch = checkGoVersion(targetMod, referenceMod, opts.GoPolicy)
+	}
+
+	// Check toolchain
+	if opts.Directives[DirectiveToolchain] {
+		if change := CompareToolchain(targetMod, referenceMod); change != nil {
+			result.ToolchainMismatch = &ToolchainMismatch{
+				TargetVersion:    change.OldVersion,
+				ReferenceVersion: change.NewVersion,
+			}
+		}
+	}
+
+	// Check godebug settings
+	if opts.Directives[DirectiveGodebug] {
+		for _, change := range CompareGodebug(targetMod, referenceMod) {
+			result.GodebugMismatches = append(result.GodebugMismatches, GodebugMismatch{
+				Key:            change.Key,
+				TargetValue:    change.OldValue,
+				ReferenceValue: change.NewValue,
+			})
+		}
+	}
+
+	// Check tool directives
+	if opts.Directives[DirectiveTool] {
+		result.MissingTools = CompareTools(targetMod, referenceMod)
+	}
+}
+
+// checkGoVersion compares the go directives of target and reference under the
+// policy and returns the mismatch, or nil if the reference has no go directive
+// or the target version is acceptable
+func checkGoVersion(targetMod, referenceMod *modfile.File, policy GoVersionPolicy) *GoVersionMismatch {
 	var targetGoVersion, refGoVersion string
 	if targetMod.Go != nil {
 		targetGoVersion = targetMod.Go.Version
@@ -43,12 +146,11 @@ func CheckVersions(targetMod, referenceMod *modfile.File, strict bool) *CheckRes
 		refGoVersion = referenceMod.Go.Version
 	}
 
-	if refGoVersion != "" && targetGoVersion != refGoVersion {
-		result.GoVersionMismatch = &GoVersionMismatch{
-			TargetVersion:    targetGoVersion,
-			ReferenceVersion: refGoVersion,
-		}
+	if refGoVersion == "" || !policy.ShouldUpdate(targetGoVersion, refGoVersion) {
+		return nil
+	}
+	return &GoVersionMismatch{
+		TargetVersion:    targetGoVersion,
+		ReferenceVersion: refGoVersion,
 	}
-
-	return result
 }
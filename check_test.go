@@ -245,3 +245,192 @@ require (
 		})
 	}
 }
+
+func TestCheckVersionsWithOptions_Replaces(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.2
+`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.21
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3
+`)
+	require.NoError(t, err)
+
+	result := CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{})
+	assert.Empty(t, result.ReplaceMismatches, "replaces are not compared unless enabled")
+
+	result = CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Replaces: true, LocalReplaces: LocalReplacesKeep})
+	assert.Empty(t, result.DependencyMismatches)
+	require.Len(t, result.ReplaceMismatches, 1)
+	assert.Equal(t, ReplaceMismatch{
+		Module:                      "github.com/pkg/errors",
+		TargetReplacement:           "github.com/fork/errors",
+		TargetReplacementVersion:    "v0.9.2",
+		ReferenceReplacement:        "github.com/fork/errors",
+		ReferenceReplacementVersion: "v0.9.3",
+	}, result.ReplaceMismatches[0])
+	assert.Equal(t, 1, result.TotalMismatches())
+}
+
+func TestCheckVersionsWithOptions_Excludes(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require golang.org/x/crypto v0.2.0
+`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.21
+
+exclude golang.org/x/crypto v0.2.0
+`)
+	require.NoError(t, err)
+
+	result := CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{})
+	assert.Equal(t, 0, result.TotalMismatches(), "excludes are not checked unless enabled")
+
+	result = CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Excludes: true})
+	assert.Equal(t, []ExcludedRequirement{{Module: "golang.org/x/crypto", Version: "v0.2.0"}}, result.ExcludedRequirements)
+	assert.Equal(t, 1, result.TotalMismatches())
+}
+
+func TestCheckVersionsWithOptions_Directives(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.24
+
+toolchain go1.24.1
+
+godebug panicnil=1
+`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.24
+
+toolchain go1.24.2
+
+godebug (
+	panicnil=1
+	tlsrsakex=1
+)
+
+tool golang.org/x/tools/cmd/stringer
+`)
+	require.NoError(t, err)
+
+	result := CheckVersions(targetMod, referenceMod, false)
+	assert.Equal(t, 0, result.TotalMismatches(), "only the go directive is checked by default")
+
+	all, err := ParseDirectives("all")
+	require.NoError(t, err)
+	result = CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Directives: all})
+	assert.Nil(t, result.GoVersionMismatch)
+	assert.Equal(t, &ToolchainMismatch{TargetVersion: "go1.24.1", ReferenceVersion: "go1.24.2"}, result.ToolchainMismatch)
+	assert.Equal(t, []GodebugMismatch{{Key: "tlsrsakex", ReferenceValue: "1"}}, result.GodebugMismatches)
+	assert.Equal(t, []string{"golang.org/x/tools/cmd/stringer"}, result.MissingTools)
+	assert.Equal(t, 3, result.TotalMismatches())
+}
+
+func TestCheckVersionsWithOptions_Policy(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.5.0
+)`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.2
+	golang.org/x/text v0.4.0
+)`)
+	require.NoError(t, err)
+
+	result := CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Policy: PolicyExact})
+	assert.Len(t, result.DependencyMismatches, 2)
+
+	result = CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Policy: PolicyAllowAhead})
+	require.Len(t, result.DependencyMismatches, 1)
+	assert.Equal(t, "github.com/pkg/errors", result.DependencyMismatches[0].Module)
+
+	// upgrade-only still reports golang.org/x/text, which is ahead and which
+	// sync leaves alone
+	result = CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Policy: PolicyUpgradeOnly})
+	assert.Len(t, result.DependencyMismatches, 2)
+}
+
+func TestCheckVersionsWithOptions_ReverseStrict(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/extra/dep v1.0.0
+)`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.4.0
+)`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                    string
+		opts                    CheckOptions
+		expectedOnlyInTarget    []string
+		expectedOnlyInReference []string
+	}{
+		{"default", CheckOptions{}, nil, nil},
+		{"strict", CheckOptions{Strict: true}, []string{"github.com/extra/dep"}, nil},
+		{"reverse strict", CheckOptions{ReverseStrict: true}, nil, []string{"golang.org/x/text"}},
+		{"exact", CheckOptions{Strict: true, ReverseStrict: true}, []string{"github.com/extra/dep"}, []string{"golang.org/x/text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckVersionsWithOptions(targetMod, referenceMod, tt.opts)
+
+			var onlyInTarget, onlyInReference []string
+			for _, m := range result.DependencyMismatches {
+				switch {
+				case m.OnlyInTarget:
+					onlyInTarget = append(onlyInTarget, m.Module)
+				case m.OnlyInReference:
+					onlyInReference = append(onlyInReference, m.Module)
+					assert.Equal(t, "v0.4.0", m.ReferenceVersion)
+					assert.Empty(t, m.TargetVersion)
+				}
+			}
+			assert.Equal(t, tt.expectedOnlyInTarget, onlyInTarget)
+			assert.Equal(t, tt.expectedOnlyInReference, onlyInReference)
+		})
+	}
+}
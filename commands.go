@@ -5,186 +5,339 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+
+	"golang.org/x/mod/modfile"
 )
 
-//nolint:gocyclo // Command handler naturally has high complexity
-func syncCommand(args []string) {
-	fs := flag.NewFlagSet("sync", flag.ExitOnError)
-	targetFile := fs.String("target", "", "Path to the target go.mod file to be modified")
-	referenceFile := fs.String("reference", "", "Path or URL to the reference go.mod file with desired versions")
-	dryRun := fs.Bool("dry-run", false, "Show changes without modifying the target file")
-	verbose := fs.Bool("verbose", false, "Show detailed changes")
+// loadReference fetches and parses the reference (from URL or local path).
+// A go.work reference is merged into a single modfile from its modules,
+// a bin: reference is built from the build info of a Go binary, and SBOMs
+// and build list dumps are converted by parseReferenceData.
+func loadReference(reference string) (*modfile.File, error) {
+	// A latest reference is resolved per target; the shared reference is empty
+	if _, ok := ParseLatestMode(reference); ok {
+		return ParseGoMod(reference, nil)
+	}
+	if isBinaryReference(reference) {
+		return loadBinaryReference(reference)
+	}
+	if IsWorkFile(reference) {
+		return loadWorkspaceReference(reference)
+	}
 
-	// ExitOnError flag handles parse errors automatically
-	_ = fs.Parse(args) //nolint:errcheck // ExitOnError handles this
+	referenceData, err := FetchReference(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reference: %w", err)
+	}
 
-	if *targetFile == "" || *referenceFile == "" {
-		fmt.Println("Usage: gomodsync sync -target <target-go.mod> -reference <reference-go.mod|URL> [-dry-run] [-verbose]")
-		fmt.Println("\nOptions:")
-		fs.PrintDefaults()
-		os.Exit(1)
+	referenceMod, err := parseReferenceData(GetReferenceDisplayName(reference), referenceData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reference: %w", err)
+	}
+	return referenceMod, nil
+}
+
+// parseReferenceData parses fetched reference content. SBOMs and build list
+// dumps are detected from the content; anything else is parsed as go.mod.
+func parseReferenceData(name string, data []byte) (*modfile.File, error) {
+	if DetectSBOMFormat(data) != "" {
+		return BuildSBOMReference(name, data)
+	}
+	switch DetectBuildListFormat(data) {
+	case BuildListGoList:
+		return BuildGoListReference(name, data)
+	case BuildListModGraph:
+		return BuildModGraphReference(name, data)
+	default:
+		return ParseGoMod(name, data)
+	}
+}
+
+// readTarget reads and parses a target go.mod file
+func readTarget(path string) (*modfile.File, error) {
+	targetData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target file: %w", err)
 	}
 
-	// Read and parse the target file
-	targetData, err := os.ReadFile(*targetFile)
+	targetMod, err := ParseGoMod(path, targetData)
 	if err != nil {
-		log.Fatalf("Failed to read target file: %v", err)
+		return nil, fmt.Errorf("failed to parse target file: %w", err)
 	}
+	return targetMod, nil
+}
 
+// syncTarget syncs a single target file against the reference and returns the
+// result, the updated modfile and a unified diff of the file. Unless dryRun is
+// set, the updated file is written back with its original permissions.
+func syncTarget(path string, referenceMod *modfile.File, opts SyncOptions, dryRun bool) (*SyncResult, *modfile.File, string, error) {
 	// Get original file permissions to preserve them
-	targetInfo, err := os.Stat(*targetFile)
+	targetInfo, err := os.Stat(path)
 	if err != nil {
-		log.Fatalf("Failed to stat target file: %v", err)
+		return nil, nil, "", fmt.Errorf("failed to stat target file: %w", err)
 	}
 	targetPerms := targetInfo.Mode().Perm()
 
-	targetMod, err := ParseGoMod(*targetFile, targetData)
+	targetData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to read target file: %w", err)
+	}
+
+	targetMod, err := ParseGoMod(path, targetData)
 	if err != nil {
-		log.Fatalf("Failed to parse target file: %v", err)
+		return nil, nil, "", fmt.Errorf("failed to parse target file: %w", err)
+	}
+
+	if opts.Latest != nil {
+		if referenceMod, err = opts.Latest.Reference(targetMod); err != nil {
+			return nil, nil, "", fmt.Errorf("failed to resolve latest versions: %w", err)
+		}
+	}
+	if err := opts.Constraints.loadCandidates(targetMod); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to load policy file candidates: %w", err)
 	}
 
-	// Fetch and parse the reference (from URL or local path)
-	referenceData, err := FetchReference(*referenceFile)
+	result, err := SyncVersionsWithOptions(targetMod, referenceMod, opts)
 	if err != nil {
-		log.Fatalf("Failed to fetch reference: %v", err)
+		return nil, nil, "", fmt.Errorf("failed to sync versions: %w", err)
 	}
 
-	referenceName := GetReferenceDisplayName(*referenceFile)
-	referenceMod, err := ParseGoMod(referenceName, referenceData)
+	if result.TotalChanges() == 0 {
+		return result, targetMod, "", nil
+	}
+
+	// Format the updated target file
+	formatted, err := targetMod.Format()
 	if err != nil {
-		log.Fatalf("Failed to parse reference: %v", err)
+		return nil, nil, "", fmt.Errorf("failed to format target file: %w", err)
 	}
 
-	// Sync versions
-	result, err := SyncVersions(targetMod, referenceMod)
+	diff, err := UnifiedDiff(path, targetData, formatted)
 	if err != nil {
-		log.Fatalf("Failed to sync versions: %v", err)
+		return nil, nil, "", fmt.Errorf("failed to diff target file: %w", err)
 	}
 
-	totalChanges := len(result.DependencyChanges)
-	if result.GoVersionChange != nil {
-		totalChanges++
+	if dryRun {
+		return result, targetMod, diff, nil
 	}
 
-	if totalChanges == 0 {
-		fmt.Println("✓ No version differences found. Target file is already in sync.")
-		return
+	// Write with original file permissions
+	if err := os.WriteFile(path, formatted, targetPerms); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to write target file: %w", err)
 	}
 
-	// Print changes if verbose
-	if *verbose {
-		fmt.Printf("Changes to be made:\n\n")
+	return result, targetMod, diff, nil
+}
 
-		if result.GoVersionChange != nil {
-			fmt.Printf("  go: %s -> %s\n", result.GoVersionChange.OldVersion, result.GoVersionChange.NewVersion)
-		}
+// referenceName returns the name reports give the reference: the reference
+// layers, or the policy file when it is used without a reference
+func referenceName(references []string, policyFile string) string {
+	if len(references) == 0 {
+		return GetReferenceDisplayName(policyFile)
+	}
+	return referenceDisplayNames(references)
+}
+
+//nolint:gocyclo // Command handler naturally has high complexity
+func syncCommand(args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	targetFile := fs.String("target", "", "Path to the target go.mod file to be modified, or a go.work file to modify all its modules")
+	targetsPattern := fs.String("targets", "", "Comma-separated directory patterns (e.g. ./...) whose go.mod files are all modified")
+	skipDirs := fs.String("skip", strings.Join(defaultSkipDirs, ","), "Comma-separated directory names to skip when discovering -targets")
+	var references referenceList
+	fs.Var(&references, "reference", "Path, URL, proxy:module@version, git:repo@ref:path or bin:binary of the reference go.mod (or go.work) file with desired versions, or latest, latest-minor or latest-patch; repeat or comma-separate to layer references, later layers win")
+	dryRun := fs.Bool("dry-run", false, "Show changes without modifying the target file")
+	verbose := fs.Bool("verbose", false, "Show detailed changes")
+	replaces := fs.Bool("replaces", false, "Also add, update and remove replace directives to match the reference")
+	localReplaces := fs.String("local-replaces", string(LocalReplacesKeep), "How replaces pointing at local directories are handled: keep or sync")
+	excludes := fs.Bool("excludes", true, "Copy exclude directives from the reference and move requirements off excluded versions (-excludes=false to disable)")
+	directives := fs.String("directives", string(DirectiveGo), "Comma-separated directives to sync: "+directiveNames()+", all or none")
+	policy := fs.String("policy", string(PolicyExact), "Version policy: exact, upgrade-only, allow-ahead or same-major")
+	policyFile := fs.String("policy-file", "", "Path or URL to a .gomodsync.yaml file with per-module version constraints")
+	goPolicy := fs.String("go-policy", string(GoPolicyExact), "Go directive policy: exact, at-least-reference or at-most-reference")
+	goMax := fs.String("go-max", "", "Never raise the go directive above this version (e.g. 1.22)")
+	addMissing := fs.Bool("add-missing", false, "Add reference dependencies that the target does not require")
+	addMissingAs := fs.String("add-missing-as", "indirect", "How dependencies added by -add-missing are marked: direct or indirect")
+	prune := fs.Bool("prune", false, "Remove target dependencies that are not in the reference")
+	format := fs.String("format", string(FormatText), "Output format: text or json")
+	showDiff := fs.Bool("diff", false, "Show a unified diff of each changed go.mod file")
+	patchFile := fs.String("patch", "", "Write a unified diff of all changes to this file (requires -dry-run)")
+	addFetchFlags(fs)
 
-		for _, change := range result.DependencyChanges {
-			fmt.Printf("  %s: %s -> %s\n", change.Module, change.OldVersion, change.NewVersion)
+	// ExitOnError flag handles parse errors automatically
+	_ = fs.Parse(args) //nolint:errcheck // ExitOnError handles this
+	fetchSettings.AuthHosts = credentialHosts(append([]string{*policyFile}, references...))
+
+	if (*targetFile == "") == (*targetsPattern == "") || (len(references) == 0 && *policyFile == "") {
+		fmt.Println("Usage: gomodsync sync (-target <target-go.mod> | -targets <pattern>) (-reference <reference-go.mod|URL>... | -policy-file <file>) [-dry-run] [-verbose] [-policy <policy>] [-policy-file <file>] [-go-policy <policy>] [-go-max <version>] [-add-missing] [-prune] [-directives <list>] [-replaces] [-excludes=false] [-diff] [-patch <file>] [-format text|json] [-timeout <duration>] [-retries <n>] [-max-size <bytes>] [-auth-header <header>] [-allow-insecure-auth] [-ca-file <file>] [-client-cert <file> -client-key <file>] [-pin-sha256 <hash>] [-cache-dir <dir>] [-offline]")
+		fmt.Println("\nOptions:")
+		fs.PrintDefaults()
+		os.Exit(1)
+	}
+
+	targets, err := ResolveTargets(*targetFile, *targetsPattern, splitList(*skipDirs))
+	if err != nil {
+		log.Fatalf("Failed to resolve targets: %v", err)
+	}
+
+	latest, err := latestReference(references)
+	if err != nil {
+		log.Fatalf("Invalid -reference: %v", err)
+	}
+	referenceMod, sources, err := loadReferences(references)
+	if err != nil {
+		log.Fatalf("Failed to load reference: %v", err)
+	}
+
+	opts := SyncOptions{AddMissing: *addMissing, Prune: *prune, Replaces: *replaces, Excludes: *excludes, Sources: sources, Latest: latest}
+	switch *addMissingAs {
+	case "direct":
+	case "indirect":
+		opts.AddMissingIndirect = true
+	default:
+		log.Fatalf("Invalid -add-missing-as: %q (expected direct or indirect)", *addMissingAs)
+	}
+	if opts.LocalReplaces, err = ParseLocalReplacePolicy(*localReplaces); err != nil {
+		log.Fatalf("Invalid -local-replaces: %v", err)
+	}
+	if opts.Directives, err = ParseDirectives(*directives); err != nil {
+		log.Fatalf("Invalid -directives: %v", err)
+	}
+	if opts.Policy, err = ParseVersionPolicy(*policy); err != nil {
+		log.Fatalf("Invalid -policy: %v", err)
+	}
+	if opts.GoPolicy, err = ParseGoVersionPolicy(*goPolicy); err != nil {
+		log.Fatalf("Invalid -go-policy: %v", err)
+	}
+	if *policyFile != "" {
+		if opts.Constraints, err = LoadConstraintFile(*policyFile); err != nil {
+			log.Fatalf("Invalid -policy-file: %v", err)
 		}
-		fmt.Println()
-	}
-
-	if *dryRun {
-		fmt.Printf("Dry-run mode: %d change(s) identified but not applied.\n", totalChanges)
-		if *verbose {
-			fmt.Println("\nPreview of updated go.mod:")
-			fmt.Println("---")
-			previewData, fmtErr := targetMod.Format()
-			if fmtErr != nil {
-				log.Fatalf("Failed to format target file: %v", fmtErr)
-			}
-			fmt.Println(string(previewData))
+	}
+	if *goMax != "" {
+		if err := ValidateGoVersion(*goMax); err != nil {
+			log.Fatalf("Invalid -go-max: %v", err)
 		}
-		return
+		opts.GoMax = *goMax
 	}
 
-	// Format and write the updated target file
-	formatted, err := targetMod.Format()
+	outputFormat, err := ParseOutputFormat(*format, FormatText, FormatJSON)
 	if err != nil {
-		log.Fatalf("Failed to format target file: %v", err)
+		log.Fatalf("Invalid -format: %v", err)
+	}
+	if *patchFile != "" && !*dryRun {
+		log.Fatalf("-patch requires -dry-run")
 	}
 
-	// Write with original file permissions
-	if err := os.WriteFile(*targetFile, formatted, targetPerms); err != nil {
-		log.Fatalf("Failed to write target file: %v", err)
+	report := RunSync(targets, referenceName(references, *policyFile), referenceMod, opts, *dryRun)
+
+	if outputFormat == FormatJSON {
+		writeReport(report)
+	} else {
+		single := *targetsPattern == "" && !IsWorkFile(*targetFile)
+		printSyncText(report, single, *verbose, *showDiff)
+	}
+
+	if *patchFile != "" {
+		if err := writePatch(*patchFile, report); err != nil {
+			log.Fatalf("Failed to write patch: %v", err)
+		}
 	}
 
-	fmt.Printf("✓ Successfully updated %s (%d change(s) applied)\n", *targetFile, totalChanges)
+	if report.FailedFiles > 0 {
+		os.Exit(1)
+	}
 }
 
+//nolint:gocyclo // Command handler naturally has high complexity
 func checkCommand(args []string) {
 	fs := flag.NewFlagSet("check", flag.ExitOnError)
-	targetFile := fs.String("target", "", "Path to the target go.mod file to check")
-	referenceFile := fs.String("reference", "", "Path or URL to the reference go.mod file with desired versions")
+	targetFile := fs.String("target", "", "Path to the target go.mod file to check, or a go.work file to check all its modules")
+	targetsPattern := fs.String("targets", "", "Comma-separated directory patterns (e.g. ./...) whose go.mod files are all checked")
+	skipDirs := fs.String("skip", strings.Join(defaultSkipDirs, ","), "Comma-separated directory names to skip when discovering -targets")
+	var references referenceList
+	fs.Var(&references, "reference", "Path, URL, proxy:module@version, git:repo@ref:path or bin:binary of the reference go.mod (or go.work) file with desired versions, or latest, latest-minor or latest-patch; repeat or comma-separate to layer references, later layers win")
 	strict := fs.Bool("strict", false, "Fail if target has dependencies not in reference")
+	reverseStrict := fs.Bool("reverse-strict", false, "Fail if reference has dependencies not in target")
+	exact := fs.Bool("exact", false, "Fail if the dependency sets differ in either direction (-strict and -reverse-strict)")
 	verbose := fs.Bool("verbose", false, "Show detailed version mismatches")
+	replaces := fs.Bool("replaces", false, "Also fail if replace directives differ from the reference")
+	localReplaces := fs.String("local-replaces", string(LocalReplacesKeep), "How replaces pointing at local directories are handled: keep or sync")
+	excludes := fs.Bool("excludes", true, "Fail if target requires a version the reference excludes (-excludes=false to disable)")
+	directives := fs.String("directives", string(DirectiveGo), "Comma-separated directives to check: "+directiveNames()+", all or none")
+	policy := fs.String("policy", string(PolicyExact), "Version policy: exact, upgrade-only, allow-ahead or same-major")
+	policyFile := fs.String("policy-file", "", "Path or URL to a .gomodsync.yaml file with per-module version constraints")
+	goPolicy := fs.String("go-policy", string(GoPolicyExact), "Go directive policy: exact, at-least-reference or at-most-reference")
+	format := fs.String("format", string(FormatText), "Output format: text, json, sarif, github or junit")
+	addFetchFlags(fs)
 
 	// ExitOnError flag handles parse errors automatically
 	_ = fs.Parse(args) //nolint:errcheck // ExitOnError handles this
+	fetchSettings.AuthHosts = credentialHosts(append([]string{*policyFile}, references...))
 
-	if *targetFile == "" || *referenceFile == "" {
-		fmt.Println("Usage: gomodsync check -target <target-go.mod> -reference <reference-go.mod|URL> [-strict] [-verbose]")
+	if (*targetFile == "") == (*targetsPattern == "") || (len(references) == 0 && *policyFile == "") {
+		fmt.Println("Usage: gomodsync check (-target <target-go.mod> | -targets <pattern>) (-reference <reference-go.mod|URL>... | -policy-file <file>) [-strict] [-reverse-strict] [-exact] [-verbose] [-policy <policy>] [-policy-file <file>] [-go-policy <policy>] [-directives <list>] [-replaces] [-excludes=false] [-format text|json|sarif|github|junit] [-timeout <duration>] [-retries <n>] [-max-size <bytes>] [-auth-header <header>] [-allow-insecure-auth] [-ca-file <file>] [-client-cert <file> -client-key <file>] [-pin-sha256 <hash>] [-cache-dir <dir>] [-offline]")
 		fmt.Println("\nOptions:")
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
 
-	// Read and parse the target file
-	targetData, err := os.ReadFile(*targetFile)
+	targets, err := ResolveTargets(*targetFile, *targetsPattern, splitList(*skipDirs))
 	if err != nil {
-		log.Fatalf("Failed to read target file: %v", err)
+		log.Fatalf("Failed to resolve targets: %v", err)
 	}
 
-	targetMod, err := ParseGoMod(*targetFile, targetData)
+	latest, err := latestReference(references)
 	if err != nil {
-		log.Fatalf("Failed to parse target file: %v", err)
+		log.Fatalf("Invalid -reference: %v", err)
 	}
-
-	// Fetch and parse the reference (from URL or local path)
-	referenceData, err := FetchReference(*referenceFile)
+	referenceMod, sources, err := loadReferences(references)
 	if err != nil {
-		log.Fatalf("Failed to fetch reference: %v", err)
+		log.Fatalf("Failed to load reference: %v", err)
 	}
 
-	referenceName := GetReferenceDisplayName(*referenceFile)
-	referenceMod, err := ParseGoMod(referenceName, referenceData)
-	if err != nil {
-		log.Fatalf("Failed to parse reference: %v", err)
+	opts := CheckOptions{
+		Strict:        *strict || *exact,
+		ReverseStrict: *reverseStrict || *exact,
+		Replaces:      *replaces,
+		Excludes:      *excludes,
+		Sources:       sources,
+		Latest:        latest,
 	}
-
-	// Check versions
-	result := CheckVersions(targetMod, referenceMod, *strict)
-
-	totalMismatches := len(result.DependencyMismatches)
-	if result.GoVersionMismatch != nil {
-		totalMismatches++
+	if opts.LocalReplaces, err = ParseLocalReplacePolicy(*localReplaces); err != nil {
+		log.Fatalf("Invalid -local-replaces: %v", err)
 	}
-
-	if totalMismatches == 0 {
-		fmt.Println("✓ All versions match (dependencies and Go version)!")
-		os.Exit(0)
+	if opts.Directives, err = ParseDirectives(*directives); err != nil {
+		log.Fatalf("Invalid -directives: %v", err)
+	}
+	if opts.Policy, err = ParseVersionPolicy(*policy); err != nil {
+		log.Fatalf("Invalid -policy: %v", err)
+	}
+	if opts.GoPolicy, err = ParseGoVersionPolicy(*goPolicy); err != nil {
+		log.Fatalf("Invalid -go-policy: %v", err)
+	}
+	if *policyFile != "" {
+		if opts.Constraints, err = LoadConstraintFile(*policyFile); err != nil {
+			log.Fatalf("Invalid -policy-file: %v", err)
+		}
 	}
 
-	// Print summary or detailed mismatches based on verbose flag
-	if *verbose {
-		fmt.Printf("✗ Found %d version mismatch(es):\n\n", totalMismatches)
+	outputFormat, err := ParseOutputFormat(*format, FormatText, FormatJSON, FormatSARIF, FormatGitHub, FormatJUnit)
+	if err != nil {
+		log.Fatalf("Invalid -format: %v", err)
+	}
 
-		if result.GoVersionMismatch != nil {
-			fmt.Printf("  go: %s != %s\n", result.GoVersionMismatch.TargetVersion, result.GoVersionMismatch.ReferenceVersion)
-		}
+	report := RunCheck(targets, referenceName(references, *policyFile), referenceMod, opts)
 
-		for _, mismatch := range result.DependencyMismatches {
-			if mismatch.OnlyInTarget {
-				fmt.Printf("  %s: %s (not in reference)\n", mismatch.Module, mismatch.TargetVersion)
-			} else {
-				fmt.Printf("  %s: %s != %s\n", mismatch.Module, mismatch.TargetVersion, mismatch.ReferenceVersion)
-			}
-		}
+	if outputFormat == FormatText {
+		single := *targetsPattern == "" && !IsWorkFile(*targetFile)
+		printCheckText(report, single, *verbose)
 	} else {
-		fmt.Printf("✗ Version check failed: %d mismatch(es) found\n", totalMismatches)
-		fmt.Println("Run with -verbose to see details")
+		writeCheckReport(report, outputFormat)
 	}
 
-	os.Exit(1)
+	if !report.Passed {
+		os.Exit(1)
+	}
 }
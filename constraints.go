@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// ConstraintKind is the kind of a module version constraint
+type ConstraintKind string
+
+// Supported constraint kinds
+const (
+	// ConstraintRange requires the version to satisfy every comparator, e.g. >=v1.8.0 <v2
+	ConstraintRange ConstraintKind = "range"
+	// ConstraintExact requires one exact version, e.g. exact v1.2.3
+	ConstraintExact ConstraintKind = "exact"
+	// ConstraintLatestPatch requires the newest known patch release of the current minor version
+	ConstraintLatestPatch ConstraintKind = "latest-patch"
+	// ConstraintDeny forbids requiring the module at all
+	ConstraintDeny ConstraintKind = "deny"
+	// ConstraintIgnore leaves the module alone
+	ConstraintIgnore ConstraintKind = "ignore"
+)
+
+// VersionPreference selects which satisfying version sync picks
+type VersionPreference string
+
+// Supported version preferences. The zero value behaves like PreferHighest.
+const (
+	PreferHighest VersionPreference = "highest"
+	PreferLowest  VersionPreference = "lowest"
+)
+
+// CandidateSource selects where sync and check look for versions that
+// satisfy a constraint
+type CandidateSource string
+
+// Supported candidate sources. The zero value behaves like CandidatesReference.
+const (
+	// CandidatesReference uses the versions required by every reference layer
+	CandidatesReference CandidateSource = "reference"
+	// CandidatesProxy also uses the releases listed by the module proxy
+	CandidatesProxy CandidateSource = "proxy"
+)
+
+// comparator is a single bound of a range constraint
+type comparator struct {
+	op      string
+	version string
+}
+
+// allows reports whether version satisfies the comparator
+func (c comparator) allows(version string) bool {
+	cmp := semver.Compare(version, c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Constraint is a parsed module version constraint
+type Constraint struct {
+	Raw         string
+	Kind        ConstraintKind
+	comparators []comparator
+}
+
+// comparatorOps lists the range operators, longest first so prefixes match correctly
+var comparatorOps = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses a constraint such as ">=v1.8.0 <v2", "~v0.30",
+// "exact v1.2.3", "latest-patch", "deny" or "ignore"
+func ParseConstraint(raw string) (*Constraint, error) {
+	text := strings.TrimSpace(raw)
+	constraint := &Constraint{Raw: text}
+
+	switch {
+	case text == string(ConstraintDeny), text == string(ConstraintIgnore), text == string(ConstraintLatestPatch):
+		constraint.Kind = ConstraintKind(text)
+	case strings.HasPrefix(text, "exact "):
+		version := strings.TrimSpace(strings.TrimPrefix(text, "exact "))
+		if semver.Canonical(version) != version || semver.Build(version) != "" {
+			return nil, fmt.Errorf("invalid constraint %q: exact needs a full version such as v1.2.3", raw)
+		}
+		constraint.Kind = ConstraintExact
+		constraint.comparators = []comparator{{op: "=", version: version}}
+	case strings.HasPrefix(text, "~"):
+		lower, upper, err := tildeRange(strings.TrimPrefix(text, "~"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", raw, err)
+		}
+		constraint.Kind = ConstraintRange
+		constraint.comparators = []comparator{{op: ">=", version: lower}, {op: "<", version: upper}}
+	default:
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " "))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty constraint")
+		}
+		for _, field := range fields {
+			c, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %w", raw, err)
+			}
+			constraint.comparators = append(constraint.comparators, c)
+		}
+		constraint.Kind = ConstraintRange
+	}
+
+	return constraint, nil
+}
+
+// parseComparator parses a single bound such as >=v1.8.0
+func parseComparator(field string) (comparator, error) {
+	for _, op := range comparatorOps {
+		if version, found := strings.CutPrefix(field, op); found {
+			if !semver.IsValid(version) {
+				return comparator{}, fmt.Errorf("%q is not a valid version", version)
+			}
+			return comparator{op: op, version: version}, nil
+		}
+	}
+	return comparator{}, fmt.Errorf("%q has no comparison operator (>=, >, <=, <, = or !=)", field)
+}
+
+// tildeRange returns the bounds of a ~ constraint: ~v0.30 allows v0.30.x,
+// ~v1.2.3 allows v1.2.3 up to v1.3.0 and ~v1 allows any v1 version
+func tildeRange(version string) (lower, upper string, err error) {
+	if !semver.IsValid(version) || semver.Prerelease(version) != "" || semver.Build(version) != "" {
+		return "", "", fmt.Errorf("%q is not a valid version", version)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		if numbers[i], err = strconv.Atoi(part); err != nil {
+			return "", "", fmt.Errorf("%q is not a valid version", version)
+		}
+	}
+
+	lower = semver.Canonical(version)
+	if len(numbers) == 1 {
+		return lower, fmt.Sprintf("v%d.0.0", numbers[0]+1), nil
+	}
+	return lower, fmt.Sprintf("v%d.%d.0", numbers[0], numbers[1]+1), nil
+}
+
+// Allows reports whether version satisfies the constraint. candidates are the
+// known versions of the module, used by latest-patch; prereleases among them
+// are not considered releases.
+func (c *Constraint) Allows(version string, candidates []string) bool {
+	switch c.Kind {
+	case ConstraintIgnore:
+		return true
+	case ConstraintDeny:
+		return false
+	case ConstraintLatestPatch:
+		if !semver.IsValid(version) {
+			return false
+		}
+		for _, candidate := range candidates {
+			if semver.Prerelease(candidate) == "" && semver.MajorMinor(candidate) == semver.MajorMinor(version) &&
+				semver.Compare(candidate, version) > 0 {
+				return false
+			}
+		}
+		return true
+	default:
+		if !semver.IsValid(version) {
+			return false
+		}
+		for _, bound := range c.comparators {
+			if !bound.allows(version) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Resolve returns the version sync should set for a module at current, or
+// "" if current satisfies the constraint or no candidate does. Exact
+// constraints resolve to their version even if it is not a candidate.
+func (c *Constraint) Resolve(current string, candidates []string, prefer VersionPreference) string {
+	if c.Kind == ConstraintIgnore || c.Kind == ConstraintDeny {
+		return ""
+	}
+
+	all := append([]string{current}, candidates...)
+	if c.Kind == ConstraintExact {
+		all = append(all, c.comparators[0].version)
+	}
+	if c.Allows(current, all) {
+		return ""
+	}
+
+	var satisfying []string
+	for _, candidate := range all[1:] {
+		if c.Kind == ConstraintLatestPatch &&
+			(semver.Prerelease(candidate) != "" || semver.MajorMinor(candidate) != semver.MajorMinor(current)) {
+			continue
+		}
+		if semver.IsValid(candidate) && c.Allows(candidate, all) {
+			satisfying = append(satisfying, candidate)
+		}
+	}
+	if len(satisfying) == 0 {
+		return ""
+	}
+
+	semver.Sort(satisfying)
+	if prefer == PreferLowest {
+		return satisfying[0]
+	}
+	return satisfying[len(satisfying)-1]
+}
+
+// constraintRule binds a constraint to a module path or glob pattern
+type constraintRule struct {
+	pattern    string
+	re         *regexp.Regexp // nil for an exact module path
+	constraint *Constraint
+}
+
+// ConstraintSet holds the module constraints of a policy file
+type ConstraintSet struct {
+	Prefer     VersionPreference
+	Candidates CandidateSource
+	rules      []constraintRule
+	proxy      *LatestResolver // lists proxy releases for CandidatesProxy
+}
+
+// constraintFile is the layout of a .gomodsync.yaml policy file
+type constraintFile struct {
+	Prefer     VersionPreference `yaml:"prefer"`
+	Candidates CandidateSource   `yaml:"candidates"`
+	Modules    map[string]string `yaml:"modules"`
+}
+
+// ParseConstraintFile parses a .gomodsync.yaml policy file
+func ParseConstraintFile(data []byte) (*ConstraintSet, error) {
+	var file constraintFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid policy file: %w", err)
+	}
+
+	set := &ConstraintSet{Prefer: file.Prefer}
+	switch set.Prefer {
+	case "":
+		set.Prefer = PreferHighest
+	case PreferHighest, PreferLowest:
+	default:
+		return nil, fmt.Errorf("invalid policy file: unknown prefer value %q (expected %s or %s)", file.Prefer, PreferHighest, PreferLowest)
+	}
+
+	set.Candidates = file.Candidates
+	switch set.Candidates {
+	case "":
+		set.Candidates = CandidatesReference
+	case CandidatesReference:
+	case CandidatesProxy:
+		set.proxy = NewLatestResolver(LatestAny)
+	default:
+		return nil, fmt.Errorf("invalid policy file: unknown candidates value %q (expected %s or %s)", file.Candidates, CandidatesReference, CandidatesProxy)
+	}
+
+	for pattern, raw := range file.Modules {
+		constraint, err := ParseConstraint(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy file: module %s: %w", pattern, err)
+		}
+		rule := constraintRule{pattern: pattern, constraint: constraint}
+		if strings.ContainsAny(pattern, "*?") {
+			rule.re = globToRegexp(pattern)
+		}
+		set.rules = append(set.rules, rule)
+	}
+
+	// Keep lookups deterministic regardless of map iteration order
+	sort.Slice(set.rules, func(i, j int) bool { return set.rules[i].pattern < set.rules[j].pattern })
+	return set, nil
+}
+
+// LoadConstraintFile fetches (from URL or local path) and parses a policy file
+func LoadConstraintFile(location string) (*ConstraintSet, error) {
+	data, err := FetchReference(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy file: %w", err)
+	}
+	return ParseConstraintFile(data)
+}
+
+// globToRegexp converts a module pattern to a regular expression. * matches
+// any sequence of characters, including slashes, and ? matches one character.
+func globToRegexp(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// Lookup returns the constraint for a module. An exact module path takes
+// precedence over patterns, and among patterns the longest match wins.
+func (s *ConstraintSet) Lookup(module string) (*Constraint, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	var best *constraintRule
+	for i := range s.rules {
+		rule := &s.rules[i]
+		if rule.re == nil {
+			if rule.pattern == module {
+				return rule.constraint, true
+			}
+			continue
+		}
+		if rule.re.MatchString(module) && (best == nil || len(rule.pattern) > len(best.pattern)) {
+			best = rule
+		}
+	}
+
+	if best == nil {
+		return nil, false
+	}
+	return best.constraint, true
+}
+
+// loadCandidates lists the proxy releases of every constrained module the
+// target requires, if the policy file takes candidates from the proxy
+func (s *ConstraintSet) loadCandidates(targetMod *modfile.File) error {
+	if s == nil || s.proxy == nil {
+		return nil
+	}
+	for _, req := range targetMod.Require {
+		if !s.governs(req.Mod.Path, ConstraintRange, ConstraintExact, ConstraintLatestPatch) {
+			continue
+		}
+		if _, err := s.proxy.moduleReleases(req.Mod.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// candidates returns the known versions of a module: the version required by
+// each reference layer and, with proxy candidates, the releases loaded by
+// loadCandidates
+func (s *ConstraintSet) candidates(module string, refVersions VersionMap, sources *ReferenceSources) []string {
+	var versions []string
+	if sources != nil {
+		for version := range sources.Versions[module] {
+			versions = append(versions, version)
+		}
+	} else if version, exists := refVersions[module]; exists {
+		versions = append(versions, version)
+	}
+	if s != nil && s.proxy != nil {
+		versions = append(versions, s.proxy.releases[module]...)
+	}
+	return versions
+}
+
+// checkConstraint checks a target requirement against its policy file constraint
+// and returns the mismatch, or nil if the version is acceptable. The reference
+// version field holds the version sync would pick, if any.
+func checkConstraint(module, targetVersion string, constraint *Constraint, candidates []string, prefer VersionPreference) *VersionMismatch {
+	if constraint.Allows(targetVersion, append([]string{targetVersion}, candidates...)) {
+		return nil
+	}
+	return &VersionMismatch{
+		Module:           module,
+		TargetVersion:    targetVersion,
+		ReferenceVersion: constraint.Resolve(targetVersion, candidates, prefer),
+		Constraint:       constraint.Raw,
+	}
+}
+
+// deniedRequirements returns the target requirements the policy file denies,
+// in target order
+func (s *ConstraintSet) deniedRequirements(targetMod *modfile.File) []ModuleChange {
+	var denied []ModuleChange
+	for _, req := range targetMod.Require {
+		if s.governs(req.Mod.Path, ConstraintDeny) {
+			denied = append(denied, ModuleChange{
+				Module:   req.Mod.Path,
+				Version:  req.Mod.Version,
+				Indirect: req.Indirect,
+			})
+		}
+	}
+	return denied
+}
+
+// deniedError reports denied requirements that sync may not remove
+func deniedError(denied []ModuleChange) error {
+	modules := make([]string, 0, len(denied))
+	for _, change := range denied {
+		modules = append(modules, change.Module+"@"+change.Version)
+	}
+	return fmt.Errorf("target requires modules the policy file denies (use -prune to remove them): %s", strings.Join(modules, ", "))
+}
+
+// governs reports whether the module has a constraint of one of the given kinds
+func (s *ConstraintSet) governs(module string, kinds ...ConstraintKind) bool {
+	constraint, ok := s.Lookup(module)
+	if !ok {
+		return false
+	}
+	for _, kind := range kinds {
+		if constraint.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// filterModuleChanges drops the changes for modules whose constraint is one of the given kinds
+func (s *ConstraintSet) filterModuleChanges(changes []ModuleChange, kinds ...ConstraintKind) []ModuleChange {
+	if s == nil {
+		return changes
+	}
+	var kept []ModuleChange
+	for _, change := range changes {
+		if !s.governs(change.Module, kinds...) {
+			kept = append(kept, change)
+		}
+	}
+	return kept
+}
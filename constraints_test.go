@@ -0,0 +1,335 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		raw     string
+		kind    ConstraintKind
+		allowed []string
+		denied  []string
+	}{
+		{">=v1.8.0 <v2", ConstraintRange, []string{"v1.8.0", "v1.12.3"}, []string{"v1.7.9", "v2.0.0", "v2.1.0+incompatible"}},
+		{">=v1.8.0, <v2", ConstraintRange, []string{"v1.9.0"}, []string{"v2.0.0"}},
+		{"~v0.30", ConstraintRange, []string{"v0.30.0", "v0.30.7"}, []string{"v0.29.9", "v0.31.0"}},
+		{"~v1.2.3", ConstraintRange, []string{"v1.2.3", "v1.2.9"}, []string{"v1.2.2", "v1.3.0"}},
+		{"~v1", ConstraintRange, []string{"v1.0.0", "v1.99.0"}, []string{"v0.9.0", "v2.0.0"}},
+		{"!=v1.2.3", ConstraintRange, []string{"v1.2.4"}, []string{"v1.2.3"}},
+		{"exact v1.2.3", ConstraintExact, []string{"v1.2.3"}, []string{"v1.2.4", "v1.2.2"}},
+		{"deny", ConstraintDeny, nil, []string{"v1.0.0"}},
+		{"ignore", ConstraintIgnore, []string{"v1.0.0", "not-a-version"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.kind, constraint.Kind)
+			for _, version := range tt.allowed {
+				assert.True(t, constraint.Allows(version, nil), "%s should satisfy %s", version, tt.raw)
+			}
+			for _, version := range tt.denied {
+				assert.False(t, constraint.Allows(version, nil), "%s should not satisfy %s", version, tt.raw)
+			}
+		})
+	}
+
+	for _, raw := range []string{"", "v1.2.3", ">=latest", "~vbad", "exact v1.2", "exact", "newest"} {
+		t.Run("invalid "+raw, func(t *testing.T) {
+			_, err := ParseConstraint(raw)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConstraintLatestPatch(t *testing.T) {
+	constraint, err := ParseConstraint("latest-patch")
+	require.NoError(t, err)
+
+	candidates := []string{"v1.2.3", "v1.2.5", "v1.2.6-rc.1", "v1.3.0"}
+	assert.False(t, constraint.Allows("v1.2.3", candidates))
+	assert.True(t, constraint.Allows("v1.2.5", candidates), "prereleases are not newer patches")
+	assert.True(t, constraint.Allows("v1.3.0", candidates))
+
+	assert.Equal(t, "v1.2.5", constraint.Resolve("v1.2.3", candidates, PreferHighest), "stays within the minor version")
+	assert.Equal(t, "", constraint.Resolve("v1.2.5", candidates, PreferHighest))
+}
+
+func TestConstraintResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		current    string
+		candidates []string
+		prefer     VersionPreference
+		expected   string
+	}{
+		{"satisfied current is kept", ">=v1.8.0 <v2", "v1.9.0", []string{"v1.10.0"}, PreferHighest, ""},
+		{"highest satisfying candidate", ">=v1.8.0 <v2", "v1.7.0", []string{"v1.8.0", "v1.10.0", "v2.0.0"}, PreferHighest, "v1.10.0"},
+		{"lowest satisfying candidate", ">=v1.8.0 <v2", "v1.7.0", []string{"v1.10.0", "v1.8.0", "v2.0.0"}, PreferLowest, "v1.8.0"},
+		{"no satisfying candidate", "~v0.30", "v0.29.0", []string{"v0.31.0"}, PreferHighest, ""},
+		{"exact without candidates", "exact v1.2.3", "v1.2.0", nil, PreferHighest, "v1.2.3"},
+		{"deny is never resolved", "deny", "v1.2.0", []string{"v1.3.0"}, PreferHighest, ""},
+		{"ignore is never resolved", "ignore", "v1.2.0", []string{"v1.3.0"}, PreferHighest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraint, err := ParseConstraint(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, constraint.Resolve(tt.current, tt.candidates, tt.prefer))
+		})
+	}
+}
+
+const testPolicyFile = `
+prefer: lowest
+modules:
+  github.com/pkg/errors: ">=v0.9.0 <v1"
+  golang.org/x/*: latest-patch
+  golang.org/x/crypto: "~v0.30"
+  k8s.io/*: ignore
+  k8s.io/client-go/*: "exact v0.30.1"
+  github.com/bad/*: deny
+`
+
+func TestParseConstraintFile(t *testing.T) {
+	set, err := ParseConstraintFile([]byte(testPolicyFile))
+	require.NoError(t, err)
+	assert.Equal(t, PreferLowest, set.Prefer)
+
+	tests := []struct {
+		module   string
+		expected string
+	}{
+		{"github.com/pkg/errors", ">=v0.9.0 <v1"},
+		{"golang.org/x/text", "latest-patch"},
+		{"golang.org/x/crypto", "~v0.30"},
+		{"golang.org/x/tools/gopls", "latest-patch"},
+		{"k8s.io/api", "ignore"},
+		{"k8s.io/client-go/v2", "exact v0.30.1"},
+		{"github.com/bad/module", "deny"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			constraint, ok := set.Lookup(tt.module)
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, constraint.Raw)
+		})
+	}
+
+	_, ok := set.Lookup("github.com/other/module")
+	assert.False(t, ok)
+
+	var nilSet *ConstraintSet
+	_, ok = nilSet.Lookup("github.com/pkg/errors")
+	assert.False(t, ok)
+}
+
+func TestParseConstraintFile_Errors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field":      "modules: {}\nreference: go.mod\n",
+		"invalid prefer":     "prefer: newest\n",
+		"invalid candidates": "candidates: github\n",
+		"invalid constraint": "modules:\n  github.com/pkg/errors: \">=latest\"\n",
+		"invalid yaml":       "modules: [\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConstraintFile([]byte(content))
+			assert.Error(t, err)
+		})
+	}
+
+	set, err := ParseConstraintFile([]byte("modules: {}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, PreferHighest, set.Prefer, "highest is the default preference")
+	assert.Equal(t, CandidatesReference, set.Candidates, "reference is the default candidate source")
+}
+
+func TestConstraintsWithSyncAndCheck(t *testing.T) {
+	set, err := ParseConstraintFile([]byte(testPolicyFile))
+	require.NoError(t, err)
+
+	target := `module example.com/test
+
+require (
+	github.com/pkg/errors v0.8.1
+	golang.org/x/text v0.14.0
+	golang.org/x/crypto v0.31.0
+	k8s.io/api v0.29.0
+	github.com/bad/module v1.0.0
+	github.com/other/module v1.0.0
+)
+`
+	reference := `module example.com/reference
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.14.2
+	golang.org/x/crypto v0.30.4
+	k8s.io/api v0.30.0
+	github.com/bad/module v1.1.0
+	github.com/other/module v1.1.0
+	github.com/bad/extra v1.0.0
+)
+`
+
+	t.Run("check", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(reference)
+		require.NoError(t, err)
+
+		result := CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{ReverseStrict: true, Constraints: set})
+		sortCheckResult(result)
+
+		assert.Equal(t, []VersionMismatch{
+			{Module: "github.com/bad/module", TargetVersion: "v1.0.0", Constraint: "deny"},
+			{Module: "github.com/other/module", TargetVersion: "v1.0.0", ReferenceVersion: "v1.1.0"},
+			{Module: "github.com/pkg/errors", TargetVersion: "v0.8.1", ReferenceVersion: "v0.9.1", Constraint: ">=v0.9.0 <v1"},
+			{Module: "golang.org/x/crypto", TargetVersion: "v0.31.0", ReferenceVersion: "v0.30.4", Constraint: "~v0.30"},
+			{Module: "golang.org/x/text", TargetVersion: "v0.14.0", ReferenceVersion: "v0.14.2", Constraint: "latest-patch"},
+		}, result.DependencyMismatches)
+	})
+
+	t.Run("check without a reference", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+		referenceMod, _, err := loadReferences(nil)
+		require.NoError(t, err)
+
+		result := CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{Constraints: set})
+		modules := make([]string, 0, len(result.DependencyMismatches))
+		for _, mismatch := range result.DependencyMismatches {
+			modules = append(modules, mismatch.Module)
+		}
+		assert.ElementsMatch(t, []string{"github.com/bad/module", "github.com/pkg/errors", "golang.org/x/crypto"}, modules)
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(reference)
+		require.NoError(t, err)
+
+		result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{AddMissing: true, Prune: true, Constraints: set})
+		require.NoError(t, err)
+		sortSyncResult(result)
+
+		assert.Equal(t, []VersionChange{
+			{Module: "github.com/other/module", OldVersion: "v1.0.0", NewVersion: "v1.1.0"},
+			{Module: "github.com/pkg/errors", OldVersion: "v0.8.1", NewVersion: "v0.9.1"},
+			{Module: "golang.org/x/crypto", OldVersion: "v0.31.0", NewVersion: "v0.30.4"},
+			{Module: "golang.org/x/text", OldVersion: "v0.14.0", NewVersion: "v0.14.2"},
+		}, result.DependencyChanges)
+		assert.Empty(t, result.AddedModules, "denied modules are never added")
+		assert.Equal(t, []ModuleChange{{Module: "github.com/bad/module", Version: "v1.0.0"}}, result.RemovedModules,
+			"-prune removes denied modules")
+
+		versions := BuildVersionMap(targetMod)
+		assert.Equal(t, "v0.29.0", versions["k8s.io/api"], "ignored modules are left alone")
+		assert.NotContains(t, versions, "github.com/bad/module")
+	})
+
+	t.Run("sync without prune rejects denied modules", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(reference)
+		require.NoError(t, err)
+
+		_, err = SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Constraints: set})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "github.com/bad/module@v1.0.0")
+		assert.Equal(t, "v0.8.1", BuildVersionMap(targetMod)["github.com/pkg/errors"], "the target is left untouched")
+	})
+}
+
+func TestConstraintsWithLayerCandidates(t *testing.T) {
+	target, err := createTestModFile("module example.com/test\n\nrequire github.com/pkg/errors v0.8.1\n")
+	require.NoError(t, err)
+	org, err := createTestModFile("module example.com/org\n\nrequire github.com/pkg/errors v0.9.0\n")
+	require.NoError(t, err)
+	team, err := createTestModFile("module example.com/team\n\nrequire github.com/pkg/errors v0.9.1\n")
+	require.NoError(t, err)
+	referenceMod, sources, err := BuildLayeredReference([]string{"org", "team"}, []*modfile.File{org, team})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		prefer   string
+		expected VersionChange
+	}{
+		{"lowest", VersionChange{Module: "github.com/pkg/errors", OldVersion: "v0.8.1", NewVersion: "v0.9.0", Source: "org"}},
+		{"highest", VersionChange{Module: "github.com/pkg/errors", OldVersion: "v0.8.1", NewVersion: "v0.9.1", Source: "team"}},
+	} {
+		t.Run(tt.prefer, func(t *testing.T) {
+			set, err := ParseConstraintFile([]byte("prefer: " + tt.prefer + "\nmodules:\n  github.com/pkg/errors: \">=v0.9.0\"\n"))
+			require.NoError(t, err)
+
+			check := CheckVersionsWithOptions(target, referenceMod, CheckOptions{Constraints: set, Sources: sources})
+			require.Len(t, check.DependencyMismatches, 1)
+			assert.Equal(t, tt.expected.NewVersion, check.DependencyMismatches[0].ReferenceVersion)
+			assert.Equal(t, tt.expected.Source, check.DependencyMismatches[0].Source)
+
+			synced, err := createTestModFile("module example.com/test\n\nrequire github.com/pkg/errors v0.8.1\n")
+			require.NoError(t, err)
+			result, err := SyncVersionsWithOptions(synced, referenceMod, SyncOptions{Constraints: set, Sources: sources})
+			require.NoError(t, err)
+			assert.Equal(t, []VersionChange{tt.expected}, result.DependencyChanges)
+		})
+	}
+}
+
+func TestConstraintsWithProxyCandidates(t *testing.T) {
+	newLatestTestProxy(t)
+	set, err := ParseConstraintFile([]byte(`
+candidates: proxy
+modules:
+  github.com/foo/a: ">=v1.1.1 <v1.4.0"
+  github.com/foo/b: "~v0.4"
+  github.com/foo/d: ignore
+`))
+	require.NoError(t, err)
+
+	targetMod, err := createTestModFile(latestTestTarget)
+	require.NoError(t, err)
+	require.NoError(t, set.loadCandidates(targetMod))
+	referenceMod, _, err := loadReferences(nil)
+	require.NoError(t, err)
+
+	result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Constraints: set})
+	require.NoError(t, err)
+	sortSyncResult(result)
+	// v1.2.0 is retracted and v1.3.0-rc.1 is a prerelease
+	assert.Equal(t, []VersionChange{
+		{Module: "github.com/foo/a", OldVersion: "v1.1.0", NewVersion: "v1.1.1"},
+		{Module: "github.com/foo/b", OldVersion: "v0.3.0", NewVersion: "v0.4.0"},
+	}, result.DependencyChanges)
+}
+
+func TestRunSync_PolicyFileOnly(t *testing.T) {
+	newLatestTestProxy(t)
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", latestTestTarget)
+	policyFile := writeTestFile(t, dir, ".gomodsync.yaml", "candidates: proxy\nmodules:\n  github.com/foo/a: \">=v1.1.1 <v1.4.0\"\n")
+
+	set, err := LoadConstraintFile(policyFile)
+	require.NoError(t, err)
+	referenceMod, _, err := loadReferences(nil)
+	require.NoError(t, err)
+
+	report := RunSync([]string{target}, referenceName(nil, policyFile), referenceMod, SyncOptions{Constraints: set}, false)
+	assert.Equal(t, policyFile, report.Reference)
+	require.Equal(t, 0, report.FailedFiles, report.Targets[0].Error)
+	assert.Equal(t, 1, report.TotalChanges)
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "github.com/foo/a v1.1.1")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+const diffContextLines = 3
+
+// noNewlineMarker follows a last line that does not end with a newline
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+// UnifiedDiff returns a unified diff from before to after for the file at path,
+// with a/ and b/ prefixes so it can be applied with git apply. It returns an
+// empty string when the contents are equal.
+func UnifiedDiff(path string, before, after []byte) (string, error) {
+	if bytes.Equal(before, after) {
+		return "", nil
+	}
+
+	// A file that cannot be named relatively is still shown by -diff; writePatch rejects it
+	name, err := patchPath(path)
+	if err != nil {
+		name = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  diffContextLines,
+	})
+}
+
+// patchPath returns the path of a file as it appears in a patch: relative to
+// the root of the git repository containing it, or to the working directory
+// outside a repository. Files outside both cannot be named in a patch.
+func patchPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	base := repositoryRoot(filepath.Dir(abs))
+	if base == "" {
+		if base, err = os.Getwd(); err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+	}
+
+	rel, err := filepath.Rel(base, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("cannot write a patch for %s: it is outside the repository and the working directory", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// repositoryRoot returns the closest directory at or above dir that contains
+// .git, or "" if there is none
+func repositoryRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// splitLines splits data into lines that each end with a newline. A last line
+// without one is followed by the "\ No newline at end of file" marker, so it
+// is printed as such and differs from the same line with a newline.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n" + noNewlineMarker
+	}
+	return lines
+}
+
+// writePatch writes the diffs of all changed targets to a single patch file.
+// It fails if a changed target cannot be named relative to its repository or
+// the working directory, as git apply would reject the patch.
+func writePatch(path string, report *SyncReport) error {
+	var patch strings.Builder
+	for _, target := range report.Targets {
+		if target.diff == "" {
+			continue
+		}
+		if _, err := patchPath(target.Target); err != nil {
+			return err
+		}
+		patch.WriteString(target.diff)
+	}
+	return os.WriteFile(path, []byte(patch.String()), 0o600)
+}
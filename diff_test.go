@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	before := []byte("module example.com/test\n\ngo 1.21\n\nrequire github.com/pkg/errors v0.9.1\n")
+	after := []byte("module example.com/test\n\ngo 1.21\n\nrequire github.com/pkg/errors v0.9.2\n")
+
+	t.Run("changed file", func(t *testing.T) {
+		diff, err := UnifiedDiff("./services/api/go.mod", before, after)
+		require.NoError(t, err)
+		assert.Equal(t, `--- a/services/api/go.mod
++++ b/services/api/go.mod
+@@ -2,4 +2,4 @@
+ 
+ go 1.21
+ 
+-require github.com/pkg/errors v0.9.1
++require github.com/pkg/errors v0.9.2
+`, diff)
+	})
+
+	t.Run("unchanged file", func(t *testing.T) {
+		diff, err := UnifiedDiff("go.mod", before, before)
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+}
+
+func TestSplitLines(t *testing.T) {
+	assert.Nil(t, splitLines(nil))
+	assert.Equal(t, []string{"a\n", "b\n"}, splitLines([]byte("a\nb\n")))
+	assert.Equal(t, []string{"a\n", "b\n\\ No newline at end of file\n"}, splitLines([]byte("a\nb")))
+}
+
+func TestRunSync_Diff(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	changed := writeTestFile(t, dir, "a/go.mod", "module example.com/a\n\nrequire github.com/pkg/errors v0.9.1\n")
+	unchanged := writeTestFile(t, dir, "b/go.mod", "module example.com/b\n\nrequire github.com/pkg/errors v0.9.2\n")
+
+	referenceMod, err := createTestModFile("module example.com/reference\n\nrequire github.com/pkg/errors v0.9.2\n")
+	require.NoError(t, err)
+
+	report := RunSync([]string{changed, unchanged}, "ref/go.mod", referenceMod, SyncOptions{}, true)
+	require.Len(t, report.Targets, 2)
+	assert.Contains(t, report.Targets[0].diff, "-require github.com/pkg/errors v0.9.1\n+require github.com/pkg/errors v0.9.2\n")
+	assert.Empty(t, report.Targets[1].diff)
+
+	patchPath := filepath.Join(dir, "out.patch")
+	require.NoError(t, writePatch(patchPath, report))
+
+	patch, err := os.ReadFile(patchPath)
+	require.NoError(t, err)
+	assert.Equal(t, report.Targets[0].diff, string(patch))
+	assert.True(t, strings.HasPrefix(string(patch), "--- a/a/go.mod\n+++ b/a/go.mod\n"))
+
+	// A target outside the working directory cannot be named in a patch
+	t.Chdir(filepath.Join(dir, "b"))
+	assert.Error(t, writePatch(patchPath, report))
+}
+
+func TestPatchPath(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+	outside := t.TempDir()
+	t.Chdir(outside)
+
+	name, err := patchPath(filepath.Join(repo, "services", "api", "go.mod"))
+	require.NoError(t, err)
+	assert.Equal(t, "services/api/go.mod", name)
+
+	name, err = patchPath("go.mod")
+	require.NoError(t, err)
+	assert.Equal(t, "go.mod", name)
+
+	_, err = patchPath(filepath.Join(filepath.Dir(outside), "elsewhere", "go.mod"))
+	assert.Error(t, err)
+}
+
+// TestUnifiedDiff_GitApply checks that generated patches apply with git,
+// including to absolute targets and files without a trailing newline
+func TestUnifiedDiff_GitApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	_, err := runGit(repo, "init", "--quiet")
+	require.NoError(t, err)
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name   string
+		before string
+		after  string
+	}{
+		{"no trailing newline", "module example.com/a\n\ngo 1.21\n\nrequire github.com/pkg/errors v0.9.1", "module example.com/a\n\ngo 1.21\n\nrequire github.com/pkg/errors v0.9.2\n"},
+		{"unchanged last line without newline", "module example.com/a\n\nrequire github.com/pkg/errors v0.9.1\n\ngo 1.21", "module example.com/a\n\nrequire github.com/pkg/errors v0.9.2\n\ngo 1.21"},
+		{"trailing newline", "module example.com/a\n\ngo 1.21\n", "module example.com/a\n\ngo 1.22\n"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Absolute paths are named relative to the repository root
+			target := writeTestFile(t, repo, fmt.Sprintf("m%d/go.mod", i), tt.before)
+			diff, err := UnifiedDiff(target, []byte(tt.before), []byte(tt.after))
+			require.NoError(t, err)
+
+			patch := writeTestFile(t, t.TempDir(), "out.patch", diff)
+			_, err = runGit(repo, "apply", patch)
+			require.NoError(t, err, diff)
+
+			data, err := os.ReadFile(target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.after, string(data))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	neturl "net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// directRepo is the git repository a module is fetched from for the direct
+// entry of GOPROXY
+type directRepo struct {
+	root string // module path of the repository root
+	url  string // git URL of the repository
+}
+
+// directRepos caches the repository of each module looked up
+var directRepos = make(map[string]*directRepo)
+
+// goImportPattern matches the go-import meta tags of a ?go-get=1 page
+var goImportPattern = regexp.MustCompile(`(?is)<meta\s+name=["']go-import["']\s+content=["']([^"']*)["']`)
+
+// fetchDirect fetches a module proxy file, "@v/list" or "@v/<version>.mod",
+// from the module's git repository instead of a proxy
+func fetchDirect(modulePath, file string) ([]byte, error) {
+	if fetchSettings.Offline {
+		return nil, fmt.Errorf("version control lookups are disabled: %w", errNotCached)
+	}
+	repo, err := lookupDirectRepo(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	return repo.fetch(modulePath, file)
+}
+
+// lookupDirectRepo finds the repository of a module like the go command does:
+// on github.com the first three path elements name the repository, and other
+// paths are resolved through the go-import meta tag served at
+// https://<module>?go-get=1
+func lookupDirectRepo(modulePath string) (*directRepo, error) {
+	if repo, exists := directRepos[modulePath]; exists {
+		return repo, nil
+	}
+
+	var repo *directRepo
+	if strings.HasPrefix(modulePath, "github.com/") {
+		parts := strings.SplitN(modulePath, "/", 4)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid github.com module path %q", modulePath)
+		}
+		root := strings.Join(parts[:3], "/")
+		repo = &directRepo{root: root, url: "https://" + root}
+	} else {
+		page, err := fetchFromURL("https://" + modulePath + "?go-get=1")
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the repository of %s: %w", modulePath, err)
+		}
+		if repo, err = parseGoImport(modulePath, page); err != nil {
+			return nil, err
+		}
+	}
+
+	directRepos[modulePath] = repo
+	return repo, nil
+}
+
+// parseGoImport returns the repository named by the go-import meta tag of a
+// ?go-get=1 page whose prefix covers the module. Only git is supported.
+func parseGoImport(modulePath string, page []byte) (*directRepo, error) {
+	var found []string
+	for _, match := range goImportPattern.FindAllSubmatch(page, -1) {
+		fields := strings.Fields(string(match[1]))
+		if len(fields) != 3 {
+			continue
+		}
+		if prefix := fields[0]; modulePath == prefix || strings.HasPrefix(modulePath, prefix+"/") {
+			if found != nil {
+				return nil, fmt.Errorf("multiple go-import meta tags found for %s", modulePath)
+			}
+			found = fields
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no go-import meta tag found for %s", modulePath)
+	}
+
+	prefix, vcs, repoURL := found[0], found[1], found[2]
+	if vcs != "git" {
+		return nil, fmt.Errorf("%s is served by %s, but only git repositories are supported", modulePath, vcs)
+	}
+	// The URL is passed to git, which would take a leading - as an option
+	if u, err := neturl.Parse(repoURL); err != nil || u.Scheme == "" || strings.HasPrefix(repoURL, "-") {
+		return nil, fmt.Errorf("invalid repository URL %q for %s", repoURL, modulePath)
+	}
+	return &directRepo{root: prefix, url: repoURL}, nil
+}
+
+// fetch serves a module proxy file from the repository
+func (r *directRepo) fetch(modulePath, file string) ([]byte, error) {
+	if file == "@v/list" {
+		return r.list(modulePath)
+	}
+	escaped, isMod := strings.CutSuffix(strings.TrimPrefix(file, "@v/"), ".mod")
+	if !isMod || !strings.HasPrefix(file, "@v/") {
+		return nil, fmt.Errorf("%s is not available from version control: %w", file, fs.ErrNotExist)
+	}
+	version, err := module.UnescapeVersion(escaped)
+	if err != nil {
+		return nil, err
+	}
+	return r.goMod(modulePath, version)
+}
+
+// moduleDir returns the directory of a module in the repository, without its
+// major version suffix, and that suffix as a directory name if it has one
+func (r *directRepo) moduleDir(modulePath string) (dir, majorDir string) {
+	dir = strings.Trim(strings.TrimPrefix(modulePath, r.root), "/")
+	if _, pathMajor, _ := module.SplitPathVersion(modulePath); strings.HasPrefix(pathMajor, "/") && strings.HasSuffix(dir, pathMajor[1:]) {
+		majorDir = pathMajor[1:]
+		dir = strings.Trim(strings.TrimSuffix(dir, majorDir), "/")
+	}
+	return dir, majorDir
+}
+
+// goMod reads the go.mod file of a module version at its tag. A major version
+// subdirectory, such as v2/go.mod, takes precedence over the module directory,
+// and a version without a go.mod file gets a synthesized one, as with the go
+// command.
+func (r *directRepo) goMod(modulePath, version string) ([]byte, error) {
+	if module.IsPseudoVersion(version) {
+		return nil, fmt.Errorf("pseudo-version %s cannot be fetched from version control; use a module proxy", version)
+	}
+
+	dir, majorDir := r.moduleDir(modulePath)
+	tag := strings.TrimSuffix(version, "+incompatible")
+	paths := []string{path.Join(dir, "go.mod")}
+	if dir != "" {
+		tag = dir + "/" + tag
+	}
+	if majorDir != "" {
+		paths = append([]string{path.Join(dir, majorDir, "go.mod")}, paths...)
+	}
+
+	data, _, err := readGitFile(r.url, "refs/tags/"+tag, true, paths...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []byte("module " + modulePath + "\n"), nil
+	}
+	return data, err
+}
+
+// list returns the versions of a module tagged in the repository, one per line
+func (r *directRepo) list(modulePath string) ([]byte, error) {
+	out, err := runGit("", "ls-remote", "--tags", "--refs", r.url)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, _ := r.moduleDir(modulePath)
+	prefix := "refs/tags/"
+	if dir != "" {
+		prefix += dir + "/"
+	}
+
+	var versions []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		version, found := strings.CutPrefix(fields[1], prefix)
+		if found && semver.Canonical(version) == version && module.Check(modulePath, version) == nil {
+			versions = append(versions, version)
+		}
+	}
+	return []byte(strings.Join(versions, "\n")), nil
+}
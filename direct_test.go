@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDirectTestRepo creates a repository for the module example.com/platform
+// with the tags v1.0.0 and v1.1.0, a nested module tagged sub/v0.1.0, a major
+// version subdirectory tagged v2.0.0 and a legacy/v1.0.0 tag without a go.mod
+// file, and serves it to the direct GOPROXY entry. It returns the repository
+// path.
+func newDirectTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	git := testGit(t, dir)
+	commit := func(tags ...string) {
+		t.Helper()
+		git("add", "-A")
+		git("commit", "--quiet", "-m", "release")
+		for _, tag := range tags {
+			git("tag", tag)
+		}
+	}
+
+	git("init", "--quiet")
+	writeTestFile(t, dir, "go.mod", "module example.com/platform\n\nrequire golang.org/x/text v0.14.0\n")
+	commit("v1.0.0", "v1.2", "not-a-version")
+	writeTestFile(t, dir, "go.mod", "module example.com/platform\n\nrequire golang.org/x/text v0.15.0\n")
+	writeTestFile(t, dir, "sub/go.mod", "module example.com/platform/sub\n")
+	writeTestFile(t, dir, "v2/go.mod", "module example.com/platform/v2\n\nrequire golang.org/x/text v0.16.0\n")
+	writeTestFile(t, dir, "legacy/main.go", "package legacy\n")
+	commit("v1.1.0", "sub/v0.1.0", "v2.0.0", "legacy/v1.0.0")
+
+	repo := &directRepo{root: "example.com/platform", url: "file://" + filepath.ToSlash(dir)}
+	for _, modulePath := range []string{"example.com/platform", "example.com/platform/sub", "example.com/platform/v2", "example.com/platform/legacy"} {
+		directRepos[modulePath] = repo
+	}
+	t.Cleanup(func() {
+		for modulePath, cached := range directRepos {
+			if cached == repo {
+				delete(directRepos, modulePath)
+			}
+		}
+	})
+	return dir
+}
+
+func TestParseGoImport(t *testing.T) {
+	tests := []struct {
+		name        string
+		module      string
+		page        string
+		expected    *directRepo
+		expectError bool
+	}{
+		{
+			name:     "module at the repository root",
+			module:   "go.example.com/platform",
+			page:     `<html><head><meta name="go-import" content="go.example.com/platform git https://git.example.com/platform.git"></head></html>`,
+			expected: &directRepo{root: "go.example.com/platform", url: "https://git.example.com/platform.git"},
+		},
+		{
+			name:   "nested module",
+			module: "go.example.com/platform/tools/v2",
+			page: `<meta name="go-import" content="go.example.com/other git https://git.example.com/other">
+<meta name='go-import' content='go.example.com/platform git ssh://git@git.example.com/platform'>`,
+			expected: &directRepo{root: "go.example.com/platform", url: "ssh://git@git.example.com/platform"},
+		},
+		{"prefix of another element", "go.example.com/platformx", `<meta name="go-import" content="go.example.com/platform git https://git.example.com/platform">`, nil, true},
+		{"no meta tag", "go.example.com/platform", `<html></html>`, nil, true},
+		{"not git", "go.example.com/platform", `<meta name="go-import" content="go.example.com/platform hg https://hg.example.com/platform">`, nil, true},
+		{"option as url", "go.example.com/platform", `<meta name="go-import" content="go.example.com/platform git -uhttps://x">`, nil, true},
+		{
+			name:   "multiple matching tags",
+			module: "go.example.com/platform/sub",
+			page: `<meta name="go-import" content="go.example.com/platform git https://git.example.com/platform">
+<meta name="go-import" content="go.example.com/platform/sub git https://git.example.com/sub">`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := parseGoImport(tt.module, []byte(tt.page))
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, repo)
+		})
+	}
+}
+
+func TestLookupDirectRepo_GitHub(t *testing.T) {
+	t.Cleanup(func() { delete(directRepos, "github.com/org/platform/tools/v2") })
+	repo, err := lookupDirectRepo("github.com/org/platform/tools/v2")
+	require.NoError(t, err)
+	assert.Equal(t, &directRepo{root: "github.com/org/platform", url: "https://github.com/org/platform"}, repo)
+
+	_, err = lookupDirectRepo("github.com/org")
+	assert.Error(t, err)
+}
+
+func TestFetchDirect(t *testing.T) {
+	newDirectTestRepo(t)
+
+	tests := []struct {
+		name        string
+		module      string
+		file        string
+		expected    string
+		expectError bool
+	}{
+		{"go.mod at a tag", "example.com/platform", "@v/v1.0.0.mod", "golang.org/x/text v0.14.0", false},
+		{"go.mod at a newer tag", "example.com/platform", "@v/v1.1.0.mod", "golang.org/x/text v0.15.0", false},
+		{"nested module tag", "example.com/platform/sub", "@v/v0.1.0.mod", "module example.com/platform/sub", false},
+		{"major version subdirectory", "example.com/platform/v2", "@v/v2.0.0.mod", "golang.org/x/text v0.16.0", false},
+		{"synthesized go.mod", "example.com/platform/legacy", "@v/v1.0.0.mod", "module example.com/platform/legacy", false},
+		{"list", "example.com/platform", "@v/list", "v1.0.0\nv1.1.0", false},
+		{"nested module list", "example.com/platform/sub", "@v/list", "v0.1.0", false},
+		{"major version list", "example.com/platform/v2", "@v/list", "v2.0.0", false},
+		{"unknown tag", "example.com/platform", "@v/v9.9.9.mod", "", true},
+		{"pseudo-version", "example.com/platform", "@v/v0.0.0-20240101000000-abcdefabcdef.mod", "", true},
+		{"latest", "example.com/platform", "@latest", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := fetchDirect(tt.module, tt.file)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if strings.HasSuffix(tt.file, "list") {
+				assert.Equal(t, tt.expected, string(data))
+				return
+			}
+			assert.Contains(t, string(data), tt.expected)
+		})
+	}
+
+	_, err := fetchDirect("example.com/platform", "@latest")
+	assert.True(t, isNotFound(err), "proxyVersions falls back to the list")
+
+	withFetchSettings(t, func(s *FetchSettings) { s.Offline = true })
+	_, err = fetchDirect("example.com/platform", "@v/v1.0.0.mod")
+	assert.ErrorIs(t, err, errNotCached)
+}
+
+func TestFetchReference_ProxyDirect(t *testing.T) {
+	newDirectTestRepo(t)
+	missing := newTestProxy(t, nil)
+
+	// A module missing from the proxy is fetched from its repository
+	t.Setenv("GOPROXY", missing.URL+",direct")
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPRIVATE", "")
+	data, err := FetchReference("proxy:example.com/platform@v1.0.0")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "golang.org/x/text v0.14.0")
+
+	// GOPRIVATE modules go straight to their repository
+	t.Setenv("GOPROXY", "off")
+	require.NoError(t, os.Unsetenv("GONOPROXY"))
+	t.Setenv("GOPRIVATE", "example.com/*")
+	data, err = FetchReference("proxy:example.com/platform@v1.1.0")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "golang.org/x/text v0.15.0")
+
+	// A latest reference lists the tags
+	versions, err := proxyVersions("example.com/platform")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, versions)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// allDirectives lists the selectable directives in display order
+var allDirectives = []Directive{DirectiveGo, DirectiveToolchain, DirectiveGodebug, DirectiveTool}
+
+// DefaultDirectives returns the directives synced and checked when none are selected
+func DefaultDirectives() DirectiveSet {
+	return DirectiveSet{DirectiveGo: true}
+}
+
+// ParseDirectives parses a comma-separated list of directive names.
+// The name "all" selects every directive and "none" selects none.
+func ParseDirectives(list string) (DirectiveSet, error) {
+	set := make(DirectiveSet)
+	for _, name := range splitList(list) {
+		switch name {
+		case "all":
+			for _, directive := range allDirectives {
+				set[directive] = true
+			}
+		case "none":
+		default:
+			directive := Directive(name)
+			if !isKnownDirective(directive) {
+				return nil, fmt.Errorf("unknown directive %q (expected one of %s, all or none)", name, directiveNames())
+			}
+			set[directive] = true
+		}
+	}
+	return set, nil
+}
+
+// isKnownDirective checks if the directive can be selected
+func isKnownDirective(directive Directive) bool {
+	for _, known := range allDirectives {
+		if directive == known {
+			return true
+		}
+	}
+	return false
+}
+
+// directiveNames returns the comma-separated names of all selectable directives
+func directiveNames() string {
+	names := make([]string, len(allDirectives))
+	for i, directive := range allDirectives {
+		names[i] = string(directive)
+	}
+	return strings.Join(names, ",")
+}
+
+// toolchainName returns the toolchain of a modfile, or an empty string if it has none
+func toolchainName(mod *modfile.File) string {
+	if mod.Toolchain == nil {
+		return ""
+	}
+	return mod.Toolchain.Name
+}
+
+// CompareToolchain returns the toolchain update needed for the target to match
+// the reference, or nil if the reference has no toolchain directive or both match
+func CompareToolchain(targetMod, referenceMod *modfile.File) *ToolchainChange {
+	targetToolchain, refToolchain := toolchainName(targetMod), toolchainName(referenceMod)
+	if refToolchain == "" || targetToolchain == refToolchain {
+		return nil
+	}
+	return &ToolchainChange{
+		OldVersion: targetToolchain,
+		NewVersion: refToolchain,
+	}
+}
+
+// buildGodebugMap creates a map of godebug keys to values from a modfile
+func buildGodebugMap(mod *modfile.File) map[string]string {
+	settings := make(map[string]string, len(mod.Godebug))
+	for _, godebug := range mod.Godebug {
+		settings[godebug.Key] = godebug.Value
+	}
+	return settings
+}
+
+// CompareGodebug returns the godebug settings of the reference that the target
+// lacks or sets to a different value, in reference order. Settings only present
+// in the target are left alone.
+func CompareGodebug(targetMod, referenceMod *modfile.File) []GodebugChange {
+	var changes []GodebugChange
+
+	targetSettings := buildGodebugMap(targetMod)
+	for _, godebug := range referenceMod.Godebug {
+		if value, exists := targetSettings[godebug.Key]; exists && value == godebug.Value {
+			continue
+		}
+		changes = append(changes, GodebugChange{
+			Key:      godebug.Key,
+			OldValue: targetSettings[godebug.Key],
+			NewValue: godebug.Value,
+		})
+	}
+
+	return changes
+}
+
+// ApplyGodebugChanges applies the godebug changes to the target modfile
+func ApplyGodebugChanges(targetMod *modfile.File, changes []GodebugChange) error {
+	for _, change := range changes {
+		if err := targetMod.AddGodebug(change.Key, change.NewValue); err != nil {
+			return fmt.Errorf("failed to set godebug %s: %w", change.Key, err)
+		}
+	}
+	return nil
+}
+
+// CompareTools returns the tool paths of the reference that the target
+// does not declare, in reference order
+func CompareTools(targetMod, referenceMod *modfile.File) []string {
+	var missing []string
+
+	existing := make(map[string]bool, len(targetMod.Tool))
+	for _, tool := range targetMod.Tool {
+		existing[tool.Path] = true
+	}
+
+	for _, tool := range referenceMod.Tool {
+		if !existing[tool.Path] {
+			existing[tool.Path] = true
+			missing = append(missing, tool.Path)
+		}
+	}
+
+	return missing
+}
+
+// ApplyToolChanges adds the tool directives to the target modfile
+func ApplyToolChanges(targetMod *modfile.File, tools []string) error {
+	for _, tool := range tools {
+		if err := targetMod.AddTool(tool); err != nil {
+			return fmt.Errorf("failed to add tool %s: %w", tool, err)
+		}
+	}
+	return nil
+}
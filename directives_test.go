@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    DirectiveSet
+		expectError bool
+	}{
+		{"default", "go", DirectiveSet{DirectiveGo: true}, false},
+		{"several", "go, toolchain,godebug", DirectiveSet{DirectiveGo: true, DirectiveToolchain: true, DirectiveGodebug: true}, false},
+		{"all", "all", DirectiveSet{DirectiveGo: true, DirectiveToolchain: true, DirectiveGodebug: true, DirectiveTool: true}, false},
+		{"none", "none", DirectiveSet{}, false},
+		{"empty", "", DirectiveSet{}, false},
+		{"unknown", "go,require", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDirectives(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCompareToolchain(t *testing.T) {
+	tests := []struct {
+		name             string
+		targetContent    string
+		referenceContent string
+		expected         *ToolchainChange
+	}{
+		{
+			name:             "update toolchain",
+			targetContent:    "module example.com/test\n\ngo 1.22\n\ntoolchain go1.22.1\n",
+			referenceContent: "module example.com/reference\n\ngo 1.22\n\ntoolchain go1.22.5\n",
+			expected:         &ToolchainChange{OldVersion: "go1.22.1", NewVersion: "go1.22.5"},
+		},
+		{
+			name:             "add toolchain",
+			targetContent:    "module example.com/test\n\ngo 1.22\n",
+			referenceContent: "module example.com/reference\n\ngo 1.22\n\ntoolchain go1.22.5\n",
+			expected:         &ToolchainChange{OldVersion: "", NewVersion: "go1.22.5"},
+		},
+		{
+			name:             "reference without toolchain",
+			targetContent:    "module example.com/test\n\ngo 1.22\n\ntoolchain go1.22.1\n",
+			referenceContent: "module example.com/reference\n\ngo 1.22\n",
+			expected:         nil,
+		},
+		{
+			name:             "same toolchain",
+			targetContent:    "module example.com/test\n\ngo 1.22\n\ntoolchain go1.22.5\n",
+			referenceContent: "module example.com/reference\n\ngo 1.22\n\ntoolchain go1.22.5\n",
+			expected:         nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetMod, err := createTestModFile(tt.targetContent)
+			require.NoError(t, err)
+			referenceMod, err := createTestModFile(tt.referenceContent)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, CompareToolchain(targetMod, referenceMod))
+		})
+	}
+}
+
+func TestCompareGodebug(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.23
+
+godebug (
+	default=go1.21
+	panicnil=1
+	x509sha1=1
+)`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.23
+
+godebug (
+	default=go1.21
+	panicnil=0
+	tlsrsakex=1
+)`)
+	require.NoError(t, err)
+
+	changes := CompareGodebug(targetMod, referenceMod)
+	assert.Equal(t, []GodebugChange{
+		{Key: "panicnil", OldValue: "1", NewValue: "0"},
+		{Key: "tlsrsakex", OldValue: "", NewValue: "1"},
+	}, changes)
+
+	require.NoError(t, ApplyGodebugChanges(targetMod, changes))
+	assert.Empty(t, CompareGodebug(targetMod, referenceMod))
+	assert.Equal(t, "1", buildGodebugMap(targetMod)["x509sha1"], "target-only settings are kept")
+}
+
+func TestCompareTools(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.24
+
+tool golang.org/x/tools/cmd/stringer`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.24
+
+tool (
+	golang.org/x/tools/cmd/stringer
+	github.com/golangci/golangci-lint/cmd/golangci-lint
+)`)
+	require.NoError(t, err)
+
+	tools := CompareTools(targetMod, referenceMod)
+	assert.Equal(t, []string{"github.com/golangci/golangci-lint/cmd/golangci-lint"}, tools)
+
+	require.NoError(t, ApplyToolChanges(targetMod, tools))
+	assert.Empty(t, CompareTools(targetMod, referenceMod))
+	assert.Len(t, targetMod.Tool, 2)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// CompareExcludes returns the exclude directives of the reference
+// that are missing from the target, in reference order
+func CompareExcludes(targetMod, referenceMod *modfile.File) []ExcludeChange {
+	var changes []ExcludeChange
+
+	existing := make(map[string]bool, len(targetMod.Exclude))
+	for _, exc := range targetMod.Exclude {
+		existing[exc.Mod.String()] = true
+	}
+
+	for _, exc := range referenceMod.Exclude {
+		if existing[exc.Mod.String()] {
+			continue
+		}
+		existing[exc.Mod.String()] = true
+		changes = append(changes, ExcludeChange{
+			Module:  exc.Mod.Path,
+			Version: exc.Mod.Version,
+		})
+	}
+
+	return changes
+}
+
+// ApplyExcludeChanges adds the exclude directives to the target modfile
+func ApplyExcludeChanges(targetMod *modfile.File, changes []ExcludeChange) error {
+	for _, change := range changes {
+		if err := targetMod.AddExclude(change.Module, change.Version); err != nil {
+			return fmt.Errorf("failed to exclude %s@%s: %w", change.Module, change.Version, err)
+		}
+	}
+	return nil
+}
+
+// FindExcludedRequirements returns the target requirements whose
+// version is excluded by the reference, in target order
+func FindExcludedRequirements(targetMod, referenceMod *modfile.File) []ExcludedRequirement {
+	var excluded []ExcludedRequirement
+
+	refExcludes := make(map[string]bool, len(referenceMod.Exclude))
+	for _, exc := range referenceMod.Exclude {
+		refExcludes[exc.Mod.String()] = true
+	}
+
+	for _, req := range targetMod.Require {
+		if refExcludes[req.Mod.String()] {
+			excluded = append(excluded, ExcludedRequirement{
+				Module:  req.Mod.Path,
+				Version: req.Mod.Version,
+			})
+		}
+	}
+
+	return excluded
+}
+
+// MoveExcludedRequirements moves target requirements on versions the reference
+// excludes to the version the reference requires and returns the changes. It
+// fails, leaving the target untouched, if any such requirement has no
+// reference version that is not excluded to move to.
+func MoveExcludedRequirements(targetMod, referenceMod *modfile.File) ([]VersionChange, error) {
+	refVersions := BuildVersionMap(referenceMod)
+	refExcludes := make(map[string]bool, len(referenceMod.Exclude))
+	for _, exc := range referenceMod.Exclude {
+		refExcludes[exc.Mod.String()] = true
+	}
+
+	var changes []VersionChange
+	var stuck []string
+	for _, excluded := range FindExcludedRequirements(targetMod, referenceMod) {
+		refVersion, exists := refVersions[excluded.Module]
+		if !exists || refExcludes[excluded.Module+"@"+refVersion] {
+			stuck = append(stuck, excluded.Module+"@"+excluded.Version)
+			continue
+		}
+		changes = append(changes, VersionChange{
+			Module:     excluded.Module,
+			OldVersion: excluded.Version,
+			NewVersion: refVersion,
+		})
+	}
+	if len(stuck) > 0 {
+		return nil, fmt.Errorf("target requires versions the reference excludes: %s", strings.Join(stuck, ", "))
+	}
+
+	if err := ApplyVersionChanges(targetMod, changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
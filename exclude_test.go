@@ -0,0 +1,175 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareExcludes(t *testing.T) {
+	tests := []struct {
+		name             string
+		targetContent    string
+		referenceContent string
+		expectedChanges  []ExcludeChange
+	}{
+		{
+			name: "copy missing excludes",
+			targetContent: `module example.com/test
+
+exclude golang.org/x/net v0.1.0`,
+			referenceContent: `module example.com/reference
+
+exclude (
+	golang.org/x/net v0.1.0
+	golang.org/x/crypto v0.2.0
+	golang.org/x/crypto v0.3.0
+)`,
+			expectedChanges: []ExcludeChange{
+				{Module: "golang.org/x/crypto", Version: "v0.2.0"},
+				{Module: "golang.org/x/crypto", Version: "v0.3.0"},
+			},
+		},
+		{
+			name: "target already has all excludes",
+			targetContent: `module example.com/test
+
+exclude golang.org/x/net v0.1.0`,
+			referenceContent: `module example.com/reference
+
+exclude golang.org/x/net v0.1.0`,
+			expectedChanges: nil,
+		},
+		{
+			name:             "reference without excludes",
+			targetContent:    `module example.com/test`,
+			referenceContent: `module example.com/reference`,
+			expectedChanges:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetMod, err := createTestModFile(tt.targetContent)
+			require.NoError(t, err, "Failed to parse target modfile")
+
+			referenceMod, err := createTestModFile(tt.referenceContent)
+			require.NoError(t, err, "Failed to parse reference modfile")
+
+			assert.Equal(t, tt.expectedChanges, CompareExcludes(targetMod, referenceMod))
+		})
+	}
+}
+
+func TestApplyExcludeChanges(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require golang.org/x/crypto v0.4.0`)
+	require.NoError(t, err)
+
+	err = ApplyExcludeChanges(targetMod, []ExcludeChange{
+		{Module: "golang.org/x/crypto", Version: "v0.2.0"},
+		{Module: "golang.org/x/crypto", Version: "v0.3.0"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, targetMod.Exclude, 2)
+
+	formatted, err := targetMod.Format()
+	require.NoError(t, err)
+	assert.Contains(t, string(formatted), "golang.org/x/crypto v0.2.0")
+	assert.Contains(t, string(formatted), "golang.org/x/crypto v0.3.0")
+}
+
+func TestFindExcludedRequirements(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+require (
+	golang.org/x/crypto v0.2.0
+	golang.org/x/net v0.5.0
+	golang.org/x/text v0.3.0 // indirect
+)`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+exclude (
+	golang.org/x/crypto v0.2.0
+	golang.org/x/net v0.1.0
+	golang.org/x/text v0.3.0
+)`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ExcludedRequirement{
+		{Module: "golang.org/x/crypto", Version: "v0.2.0"},
+		{Module: "golang.org/x/text", Version: "v0.3.0"},
+	}, FindExcludedRequirements(targetMod, referenceMod))
+}
+
+func TestMoveExcludedRequirements(t *testing.T) {
+	referenceContent := `module example.com/reference
+
+require golang.org/x/crypto v0.3.0
+
+exclude (
+	golang.org/x/crypto v0.2.0
+	golang.org/x/net v0.1.0
+)`
+
+	t.Run("moves to the reference version", func(t *testing.T) {
+		targetMod, err := createTestModFile("module example.com/test\n\nrequire golang.org/x/crypto v0.2.0\n")
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(referenceContent)
+		require.NoError(t, err)
+
+		changes, err := MoveExcludedRequirements(targetMod, referenceMod)
+		require.NoError(t, err)
+		assert.Equal(t, []VersionChange{{Module: "golang.org/x/crypto", OldVersion: "v0.2.0", NewVersion: "v0.3.0"}}, changes)
+		assert.Equal(t, "v0.3.0", BuildVersionMap(targetMod)["golang.org/x/crypto"])
+	})
+
+	t.Run("fails without a version to move to", func(t *testing.T) {
+		targetMod, err := createTestModFile("module example.com/test\n\nrequire (\n\tgolang.org/x/crypto v0.2.0\n\tgolang.org/x/net v0.1.0\n)\n")
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(referenceContent)
+		require.NoError(t, err)
+
+		_, err = MoveExcludedRequirements(targetMod, referenceMod)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "golang.org/x/net@v0.1.0")
+		// The target is left untouched
+		assert.Equal(t, "v0.2.0", BuildVersionMap(targetMod)["golang.org/x/crypto"])
+	})
+}
+
+func TestSyncThenCheck_Excludes(t *testing.T) {
+	targetMod, err := createTestModFile("module example.com/test\n\ngo 1.21\n\nrequire golang.org/x/crypto v0.2.0\n")
+	require.NoError(t, err)
+	referenceMod, err := createTestModFile("module example.com/reference\n\ngo 1.21\n\nrequire golang.org/x/crypto v0.1.0\n\nexclude golang.org/x/crypto v0.2.0\n")
+	require.NoError(t, err)
+
+	// upgrade-only would keep v0.2.0, but an excluded version is never kept
+	result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Policy: PolicyUpgradeOnly, Excludes: true})
+	require.NoError(t, err)
+	require.Len(t, result.DependencyChanges, 1)
+	assert.Equal(t, "v0.1.0", result.DependencyChanges[0].NewVersion)
+
+	assert.Empty(t, CheckVersions(targetMod, referenceMod, false).ExcludedRequirements)
+}
+
+func TestSyncVersions_ExcludesByDefault(t *testing.T) {
+	targetMod, err := createTestModFile("module example.com/test\n\ngo 1.21\n\nrequire golang.org/x/crypto v0.4.0\n")
+	require.NoError(t, err)
+	referenceMod, err := createTestModFile("module example.com/reference\n\ngo 1.21\n\nexclude golang.org/x/crypto v0.2.0\n")
+	require.NoError(t, err)
+
+	result, err := SyncVersions(targetMod, referenceMod)
+	require.NoError(t, err)
+	assert.Len(t, result.ExcludeChanges, 1)
+
+	targetMod, err = createTestModFile("module example.com/test\n\ngo 1.21\n\nrequire golang.org/x/crypto v0.2.0\n")
+	require.NoError(t, err)
+	assert.Len(t, CheckVersions(targetMod, referenceMod, false).ExcludedRequirements, 1)
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"net/http"
+	neturl "net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // isURL checks if the given string is a URL
@@ -13,42 +21,296 @@ func isURL(path string) bool {
 	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
 }
 
-// FetchReference fetches the content from either a URL or local file path.
+// FetchReference fetches the content from either a URL, a module proxy or local file path.
 // If the reference is a URL (starts with http:// or https://), it downloads the content.
+// A proxy:module@version reference fetches the module's go.mod from GOPROXY, and
+// a git:repo@ref:path or git+url@ref:path reference reads a file at a git revision.
 // Otherwise, it reads the content from the local file system.
 func FetchReference(reference string) ([]byte, error) {
+	if isProxyReference(reference) {
+		return fetchProxyReference(reference)
+	}
+	if isGitReference(reference) {
+		return fetchGitReference(reference)
+	}
 	if isURL(reference) {
 		return fetchFromURL(reference)
 	}
 	return os.ReadFile(reference)
 }
 
-// fetchFromURL downloads content from a URL
+// FetchSettings controls how references are downloaded over HTTP
+type FetchSettings struct {
+	Context      context.Context // cancels downloads, e.g. on Ctrl-C
+	Timeout      time.Duration   // limit for each request including its body; 0 disables it
+	Retries      int             // extra attempts after a 5xx or 429 response or a network error
+	MaxSize      int64           // maximum response body size in bytes; 0 disables it
+	Backoff      time.Duration   // delay before the first retry, doubled for each further one
+	MaxBackoff   time.Duration   // upper bound of a retry delay, including Retry-After
+	Headers      http.Header     // extra request headers, e.g. from -auth-header
+	AuthHosts    map[string]bool // hosts Headers and GOMODSYNC_TOKEN are sent to
+	InsecureAuth bool            // also send credentials over plain HTTP
+	TLS          TLSSettings     // CA bundle, client certificate and pinned fingerprint
+	CacheDir     string          // directory of cached responses; "" disables the cache
+	Offline      bool            // serve downloads from the cache only
+}
+
+// DefaultFetchSettings returns the settings used unless flags override them
+func DefaultFetchSettings() FetchSettings {
+	return FetchSettings{
+		Context:    context.Background(),
+		Timeout:    30 * time.Second,
+		Retries:    3,
+		MaxSize:    64 << 20,
+		Backoff:    500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+		CacheDir:   defaultCacheDir(),
+	}
+}
+
+// fetchSettings are the settings of every download
+var fetchSettings = DefaultFetchSettings()
+
+// addFetchFlags registers the flags that control downloads
+func addFetchFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&fetchSettings.Timeout, "timeout", fetchSettings.Timeout, "Timeout of each HTTP request, including reading the response (0 disables it)")
+	fs.IntVar(&fetchSettings.Retries, "retries", fetchSettings.Retries, "Retries of an HTTP request after a 5xx or 429 response or a network error")
+	fs.Int64Var(&fetchSettings.MaxSize, "max-size", fetchSettings.MaxSize, "Maximum size of an HTTP response in bytes (0 disables it)")
+	fs.Var(headerList{&fetchSettings.Headers}, "auth-header", "HTTP header \"Name: value\" sent to the hosts of -reference and -policy-file URLs, e.g. \"Authorization: Bearer ...\" (repeatable)")
+	fs.BoolVar(&fetchSettings.InsecureAuth, "allow-insecure-auth", false, "Allow sending credentials (-auth-header, GOMODSYNC_TOKEN and GOAUTH) over plain HTTP")
+	fs.StringVar(&fetchSettings.TLS.CAFile, "ca-file", "", "PEM file of CA certificates trusted for HTTPS in addition to the system roots")
+	fs.StringVar(&fetchSettings.TLS.ClientCert, "client-cert", "", "PEM client certificate for HTTPS servers requiring mutual TLS")
+	fs.StringVar(&fetchSettings.TLS.ClientKey, "client-key", "", "PEM private key of -client-cert")
+	fs.StringVar(&fetchSettings.TLS.Fingerprint, "pin-sha256", "", "SHA-256 fingerprint (hex) the HTTPS server certificate must match")
+	fs.StringVar(&fetchSettings.CacheDir, "cache-dir", fetchSettings.CacheDir, "Directory caching downloads for revalidation with ETag and Last-Modified (empty disables it)")
+	fs.BoolVar(&fetchSettings.Offline, "offline", false, "Serve downloads only from the cache, failing for anything not cached")
+}
+
+// httpStatusError reports an HTTP response other than 200 OK
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // delay requested by a Retry-After header, if any
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d %s", e.StatusCode, e.Status)
+}
+
+// fetchFromURL downloads content from a URL. Responses are cached, and a
+// cached response is revalidated with a conditional request instead of being
+// downloaded again; offline, only the cache is used.
 func fetchFromURL(url string) ([]byte, error) {
-	// #nosec G107 -- URL is user-provided via CLI flag, this is the intended functionality
-	resp, err := http.Get(url)
+	settings := fetchSettings
+	entry := loadCacheEntry(settings.CacheDir, url)
+	if settings.Offline {
+		if entry == nil {
+			return nil, fmt.Errorf("failed to fetch URL: %w", errNotCached)
+		}
+		return entry.Data, nil
+	}
+
+	header, err := requestHeaders(settings, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
+	if entry != nil {
+		addValidators(header, entry)
+	}
+	settings.Headers = header
+
+	data, respHeader, err := fetchWithRetries(settings, url)
+	var statusErr *httpStatusError
+	if entry != nil && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotModified {
+		return entry.Data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The cache only saves work, so failing to write it does not fail the download
+	if err := storeCacheEntry(settings.CacheDir, url, &cacheEntry{
+		ETag:         respHeader.Get("ETag"),
+		LastModified: respHeader.Get("Last-Modified"),
+		Data:         data,
+	}); err != nil {
+		log.Printf("Failed to cache %s: %v", redactReference(url), err)
+	}
+	return data, nil
+}
+
+// fetchWithRetries downloads a URL, retrying requests that fail with a 5xx
+// or 429 response or a network error with exponential backoff, waiting at
+// least as long as a Retry-After header asks
+func fetchWithRetries(settings FetchSettings, url string) ([]byte, http.Header, error) {
+	client, err := httpClient(settings.TLS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		data, header, err := fetchOnce(settings, client, url)
+		if err == nil {
+			return data, header, nil
+		}
+		// A cancellation racing with a failed response is still a cancellation
+		if ctxErr := settings.Context.Err(); ctxErr != nil {
+			return nil, nil, fmt.Errorf("failed to fetch URL: %w", ctxErr)
+		}
+		if attempt >= settings.Retries || !isRetryable(settings.Context, err) {
+			return data, header, err
+		}
+
+		delay := backoffDelay(settings.Backoff, settings.MaxBackoff, attempt)
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		if settings.MaxBackoff > 0 && delay > settings.MaxBackoff {
+			delay = settings.MaxBackoff
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-settings.Context.Done():
+			timer.Stop()
+			return nil, nil, fmt.Errorf("failed to fetch URL: %w", settings.Context.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// fetchOnce makes a single download attempt and returns the response body
+// and headers
+func fetchOnce(settings FetchSettings, client *http.Client, url string) ([]byte, http.Header, error) {
+	ctx := settings.Context
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	for name, values := range settings.Headers {
+		req.Header[name] = values
+	}
+	redirecting := *client
+	redirecting.CheckRedirect = dropHeadersOnRedirect(settings.Headers)
+	// #nosec G107 -- URL is user-provided via CLI flag, this is the intended functionality
+	resp, err := redirecting.Do(req)
+	if err != nil {
+		// Keep credentials in the query string out of the error message
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactReference(urlErr.URL)
+		}
+		return nil, nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch URL: HTTP %d %s", resp.StatusCode, resp.Status)
+		return nil, nil, fmt.Errorf("failed to fetch URL: %w", &httpStatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		})
+	}
+
+	body := io.Reader(resp.Body)
+	if settings.MaxSize > 0 {
+		if resp.ContentLength > settings.MaxSize {
+			return nil, nil, fmt.Errorf("response of %d bytes exceeds the maximum size of %d bytes", resp.ContentLength, settings.MaxSize)
+		}
+		body = io.LimitReader(resp.Body, settings.MaxSize+1)
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if settings.MaxSize > 0 && int64(len(data)) > settings.MaxSize {
+		return nil, nil, fmt.Errorf("response exceeds the maximum size of %d bytes", settings.MaxSize)
 	}
 
-	return data, nil
+	return data, resp.Header, nil
+}
+
+// dropHeadersOnRedirect keeps request headers, which may hold credentials,
+// from following a redirect to another host or from HTTPS to plain HTTP.
+// The net/http client only does so for a few standard headers.
+func dropHeadersOnRedirect(header http.Header) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		first := via[0].URL
+		if req.URL.Host != first.Host || (first.Scheme == "https" && req.URL.Scheme != "https") {
+			for name := range header {
+				req.Header.Del(name)
+			}
+		}
+		return nil
+	}
+}
+
+// backoffDelay returns the wait before retry attempt+1: base doubled once per
+// attempt made, no longer doubled once it reaches maxBackoff (if set) or would
+// overflow
+func backoffDelay(base, maxBackoff time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay > 0 && delay <= math.MaxInt64/2; i++ {
+		if maxBackoff > 0 && delay >= maxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	return delay
+}
+
+// isRetryable reports whether a failed download is worth another attempt:
+// 5xx and 429 responses and network errors are, unless ctx was cancelled or
+// the server certificate was rejected
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || isTLSFailure(err) {
+		return false
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *neturl.Error
+	return errors.As(err, &urlErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, given either in
+// seconds or as an HTTP date, or 0 if the header is missing or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
 }
 
 // GetReferenceDisplayName returns a display name for the reference.
-// For URLs, it returns the URL itself. For file paths, it returns the path.
+// For URLs, it returns the URL itself with any embedded credentials hidden.
+// For file paths, it returns the path. A fetched git reference also shows
+// the commit it resolved to.
 func GetReferenceDisplayName(reference string) string {
-	if isURL(reference) {
-		return reference
+	name := redactReference(reference)
+	if commit, exists := gitCommits[reference]; exists {
+		return name + " (" + commit + ")"
 	}
-	return reference
+	return name
 }
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -137,3 +143,205 @@ func TestGetReferenceDisplayName(t *testing.T) {
 		})
 	}
 }
+
+// TestMain keeps retry delays short so tests exercising retries stay fast,
+// and keeps test downloads out of the user's cache
+func TestMain(m *testing.M) {
+	fetchSettings.Backoff = time.Millisecond
+	fetchSettings.CacheDir = ""
+	os.Exit(m.Run())
+}
+
+// withFetchSettings changes the fetch settings for the duration of a test
+func withFetchSettings(t *testing.T, change func(*FetchSettings)) {
+	t.Helper()
+	saved := fetchSettings
+	change(&fetchSettings)
+	t.Cleanup(func() { fetchSettings = saved })
+}
+
+// newSequenceServer answers the n-th request with statuses[n], repeating the
+// last status, and counts the requests it receives
+func newSequenceServer(t *testing.T, statuses []int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("module example.com/test\n"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestFetchFromURL_Retries(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		retries       int
+		expectError   bool
+		expectedCalls int32
+	}{
+		{"success after 5xx", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, 3, false, 3},
+		{"success after 429", []int{http.StatusTooManyRequests, http.StatusOK}, 3, false, 2},
+		{"gives up after retries", []int{http.StatusInternalServerError}, 3, true, 4},
+		{"no retries", []int{http.StatusInternalServerError}, 0, true, 1},
+		{"404 is not retried", []int{http.StatusNotFound}, 3, true, 1},
+		{"403 is not retried", []int{http.StatusForbidden}, 3, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFetchSettings(t, func(s *FetchSettings) { s.Retries = tt.retries })
+			server, calls := newSequenceServer(t, tt.statuses, nil)
+
+			_, err := fetchFromURL(server.URL)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCalls, calls.Load())
+		})
+	}
+}
+
+func TestFetchFromURL_RetryAfter(t *testing.T) {
+	// Retry-After asks for a second; MaxBackoff caps the wait so the test stays short
+	withFetchSettings(t, func(s *FetchSettings) { s.MaxBackoff = 50 * time.Millisecond })
+	server, calls := newSequenceServer(t, []int{http.StatusTooManyRequests, http.StatusOK}, http.Header{"Retry-After": {"1"}})
+
+	start := time.Now()
+	_, err := fetchFromURL(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       time.Duration
+		maxBackoff time.Duration
+		attempt    int
+		expected   time.Duration
+	}{
+		{"first retry", time.Second, 0, 0, time.Second},
+		{"doubles per attempt", time.Second, 0, 3, 8 * time.Second},
+		{"stops doubling at the cap", time.Second, 5 * time.Second, 40, 8 * time.Second},
+		{"no backoff", 0, 0, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, backoffDelay(tt.base, tt.maxBackoff, tt.attempt))
+		})
+	}
+
+	// Without a cap, a long retry run never overflows into a negative delay
+	assert.Greater(t, backoffDelay(time.Second, 0, 100), time.Duration(math.MaxInt64/4))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"negative", "-5", 0},
+		{"HTTP date", now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{"past date", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"invalid", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseRetryAfter(tt.value, now))
+		})
+	}
+}
+
+func TestFetchFromURL_Timeout(t *testing.T) {
+	withFetchSettings(t, func(s *FetchSettings) {
+		s.Timeout = 20 * time.Millisecond
+		s.Retries = 1
+	})
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	_, err := fetchFromURL(server.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestFetchFromURL_MaxSize(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	tests := []struct {
+		name          string
+		contentLength bool
+		maxSize       int64
+		expectError   bool
+	}{
+		{"within limit", true, 100, false},
+		{"declared length over limit", true, 99, true},
+		{"streamed body over limit", false, 99, true},
+		{"limit disabled", false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFetchSettings(t, func(s *FetchSettings) { s.MaxSize = tt.maxSize })
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.contentLength {
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				}
+				_, _ = w.Write([]byte(body[:10]))
+				w.(http.Flusher).Flush()
+				_, _ = w.Write([]byte(body[10:]))
+			}))
+			defer server.Close()
+
+			data, err := fetchFromURL(server.URL)
+			if tt.expectError {
+				assert.ErrorContains(t, err, "maximum size")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, body, string(data))
+		})
+	}
+}
+
+func TestFetchFromURL_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	withFetchSettings(t, func(s *FetchSettings) {
+		s.Context = ctx
+		s.Backoff = time.Minute
+	})
+	server, calls := newSequenceServer(t, []int{http.StatusServiceUnavailable}, nil)
+
+	// Cancel while waiting for the first retry
+	go func() {
+		for calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := fetchFromURL(server.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Prefixes of references read from a git repository: git: for a local
+// repository and git+<scheme>:// for a remote one
+const (
+	gitPrefix    = "git:"
+	gitURLPrefix = "git+"
+)
+
+// gitCommits records the commit each fetched git reference resolved to, for display names
+var gitCommits = make(map[string]string)
+
+// isGitReference checks if the reference names a file at a git revision
+func isGitReference(reference string) bool {
+	return strings.HasPrefix(reference, gitPrefix) ||
+		(strings.HasPrefix(reference, gitURLPrefix) && strings.Contains(reference, "://"))
+}
+
+// gitReference is a parsed git:repo@ref:path or git+url@ref:path reference
+type gitReference struct {
+	repo   string // local repository path or remote URL
+	ref    string // tag, branch or commit
+	path   string // file path from the repository root
+	remote bool
+}
+
+// parseGitReference parses a git reference. The ref follows the last @ and the
+// file path follows the first colon after it; the path defaults to go.mod.
+func parseGitReference(reference string) (*gitReference, error) {
+	parsed := &gitReference{}
+	spec := strings.TrimPrefix(reference, gitPrefix)
+	if !strings.HasPrefix(reference, gitPrefix) {
+		spec = strings.TrimPrefix(reference, gitURLPrefix)
+		parsed.remote = true
+	}
+
+	i := strings.LastIndex(spec, "@")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid git reference %q: expected git:repo@ref[:path] or git+url@ref[:path]", reference)
+	}
+	parsed.repo = spec[:i]
+	parsed.ref, parsed.path, _ = strings.Cut(spec[i+1:], ":")
+	if parsed.path == "" {
+		parsed.path = "go.mod"
+	}
+
+	if parsed.ref == "" {
+		return nil, fmt.Errorf("invalid git reference %q: missing ref after @", reference)
+	}
+	// Values starting with - would be taken as git options
+	for _, value := range []string{parsed.repo, parsed.ref, parsed.path} {
+		if strings.HasPrefix(value, "-") {
+			return nil, fmt.Errorf("invalid git reference %q: %q must not start with -", reference, value)
+		}
+	}
+	return parsed, nil
+}
+
+// String formats the reference as git:repo@ref:path or git+url@ref:path
+func (r *gitReference) String() string {
+	prefix := gitPrefix
+	if r.remote {
+		prefix = gitURLPrefix
+	}
+	return prefix + r.repo + "@" + r.ref + ":" + r.path
+}
+
+// fetchGitReference reads the file of a git reference through the git binary
+// and records the commit it resolved to
+func fetchGitReference(reference string) ([]byte, error) {
+	parsed, err := parseGitReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	data, commit, err := readGitFile(parsed.repo, parsed.ref, parsed.remote, parsed.path)
+	if err != nil {
+		return nil, err
+	}
+	gitCommits[reference] = commit
+	return data, nil
+}
+
+// readGitFile reads the first of paths that exists at a ref of a repository
+// and returns it with the commit the ref resolved to. A remote ref is fetched
+// into a throwaway repository first, unless running -offline. If no path
+// exists, the error wraps fs.ErrNotExist.
+func readGitFile(repo, ref string, remote bool, paths ...string) ([]byte, string, error) {
+	dir := repo
+	revision := ref
+	if remote {
+		if fetchSettings.Offline {
+			return nil, "", fmt.Errorf("failed to fetch %s from %s: %w", ref, redactReference(repo), errNotCached)
+		}
+		// Fetch just the requested ref into a throwaway repository
+		var err error
+		if dir, err = os.MkdirTemp("", "gomodsync-git-"); err != nil {
+			return nil, "", fmt.Errorf("failed to create temporary repository: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		if _, err := runGit(dir, "init", "--quiet", "--bare"); err != nil {
+			return nil, "", err
+		}
+		if _, err := runGit(dir, "fetch", "--quiet", "--depth=1", repo, ref); err != nil {
+			return nil, "", err
+		}
+		revision = "FETCH_HEAD"
+	}
+
+	out, err := runGit(dir, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve %s in %s: %w", ref, repo, err)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	for _, path := range paths {
+		if _, err := runGit(dir, "cat-file", "-e", commit+":"+path); err != nil {
+			continue
+		}
+		data, err := runGit(dir, "show", commit+":"+path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s at %s: %w", path, ref, err)
+		}
+		return data, commit, nil
+	}
+	return nil, "", fmt.Errorf("failed to read %s at %s: %w", strings.Join(paths, " or "), ref, fs.ErrNotExist)
+}
+
+// runGit runs a git command in dir and returns its standard output. Git never
+// prompts for credentials, so a missing login fails instead of hanging, and
+// the command is stopped on cancellation or after the fetch timeout.
+func runGit(dir string, args ...string) ([]byte, error) {
+	ctx := fetchSettings.Context
+	if fetchSettings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fetchSettings.Timeout)
+		defer cancel()
+	}
+
+	// #nosec G204 -- arguments come from the user-provided reference, validated by parseGitReference
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
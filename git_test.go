@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testGit returns a runner for git commands in dir with a fixed identity and
+// signing disabled, failing the test on any error. It skips the test if git is
+// not installed.
+func testGit(t *testing.T, dir string) func(args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	return func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+}
+
+// newTestGitRepo creates a repository whose v2.3.0 tag holds services/api/go.mod
+// at golang.org/x/text v0.14.0, followed by a commit moving it to v0.15.0.
+// It returns the repository path and the tagged commit.
+func newTestGitRepo(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	git := testGit(t, dir)
+	write := func(version string) {
+		t.Helper()
+		writeTestFile(t, dir, "services/api/go.mod", "module example.com/api\n\ngo 1.22\n\nrequire golang.org/x/text "+version+"\n")
+	}
+
+	git("init", "--quiet")
+	write("v0.14.0")
+	writeTestFile(t, dir, "go.mod", "module example.com/platform\n\ngo 1.21\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "initial")
+	git("tag", "v2.3.0")
+	commit := git("rev-parse", "HEAD")
+
+	write("v0.15.0")
+	git("commit", "--quiet", "-am", "bump")
+	return dir, commit
+}
+
+func TestIsGitReference(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"git:../platform@v2.3.0", true},
+		{"git+https://github.com/org/platform@v2.3.0:go.mod", true},
+		{"git+ssh://git@github.com/org/platform@main", true},
+		{"git+platform@main", false},
+		{"github.com/org/platform", false},
+		{"https://example.com/go.mod", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isGitReference(tt.input))
+		})
+	}
+}
+
+func TestParseGitReference(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    *gitReference
+		expectError bool
+	}{
+		{
+			name:     "local with path",
+			input:    "git:../platform@v2.3.0:services/api/go.mod",
+			expected: &gitReference{repo: "../platform", ref: "v2.3.0", path: "services/api/go.mod"},
+		},
+		{
+			name:     "default path",
+			input:    "git:../platform@main",
+			expected: &gitReference{repo: "../platform", ref: "main", path: "go.mod"},
+		},
+		{
+			name:     "remote https",
+			input:    "git+https://github.com/org/platform.git@v2.3.0:services/api/go.mod",
+			expected: &gitReference{repo: "https://github.com/org/platform.git", ref: "v2.3.0", path: "services/api/go.mod", remote: true},
+		},
+		{
+			name:     "remote ssh with user",
+			input:    "git+ssh://git@github.com/org/platform@v1.0.0",
+			expected: &gitReference{repo: "ssh://git@github.com/org/platform", ref: "v1.0.0", path: "go.mod", remote: true},
+		},
+		{"missing ref", "git:../platform", nil, true},
+		{"empty ref", "git:../platform@:go.mod", nil, true},
+		{"empty repo", "git:@v1.0.0", nil, true},
+		{"option as ref", "git:../platform@--output=x", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseGitReference(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, parsed)
+		})
+	}
+}
+
+func TestFetchReference_Git(t *testing.T) {
+	repo, commit := newTestGitRepo(t)
+
+	tests := []struct {
+		name        string
+		reference   string
+		contains    string
+		expectError bool
+	}{
+		{"local tag", "git:" + repo + "@v2.3.0:services/api/go.mod", "golang.org/x/text v0.14.0", false},
+		{"local branch head", "git:" + repo + "@HEAD:services/api/go.mod", "golang.org/x/text v0.15.0", false},
+		{"local commit", "git:" + repo + "@" + commit + ":services/api/go.mod", "golang.org/x/text v0.14.0", false},
+		{"default path", "git:" + repo + "@v2.3.0", "module example.com/platform", false},
+		{"remote tag", "git+file://" + filepath.ToSlash(repo) + "@v2.3.0:services/api/go.mod", "golang.org/x/text v0.14.0", false},
+		{"unknown ref", "git:" + repo + "@v9.9.9", "", true},
+		{"missing file", "git:" + repo + "@v2.3.0:missing/go.mod", "", true},
+		{"not a repository", "git:" + t.TempDir() + "@main", "", true},
+		{"unknown remote ref", "git+file://" + filepath.ToSlash(repo) + "@v9.9.9", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := FetchReference(tt.reference)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, string(data), tt.contains)
+		})
+	}
+}
+
+func TestFetchReference_GitOffline(t *testing.T) {
+	repo, _ := newTestGitRepo(t)
+	withFetchSettings(t, func(s *FetchSettings) { s.Offline = true })
+
+	// Remote repositories are never fetched offline, local ones are still read
+	_, err := FetchReference("git+file://" + filepath.ToSlash(repo) + "@v2.3.0")
+	assert.ErrorIs(t, err, errNotCached)
+
+	data, err := FetchReference("git:" + repo + "@v2.3.0")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "module example.com/platform")
+}
+
+func TestGetReferenceDisplayName_Git(t *testing.T) {
+	repo, commit := newTestGitRepo(t)
+	reference := "git:" + repo + "@v2.3.0:services/api/go.mod"
+
+	referenceMod, err := loadReference(reference)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.14.0", BuildVersionMap(referenceMod)["golang.org/x/text"])
+	assert.Equal(t, reference+" ("+commit+")", GetReferenceDisplayName(reference))
+	assert.Equal(t, reference+" ("+commit+")", referenceDisplayNames([]string{reference}))
+}
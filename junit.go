@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the test cases of a single target go.mod file
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// failure marks the test case as failed, recording the target and reference values
+func (tc *junitTestCase) failure(ruleID, message, targetValue, referenceValue string) {
+	tc.Failure = &junitProblem{
+		Message: message,
+		Type:    ruleID,
+		Text:    fmt.Sprintf("target: %s\nreference: %s", formatOptional(targetValue), formatOptional(referenceValue)),
+	}
+}
+
+// buildJUnit converts a check report into JUnit test suites, one per target.
+// Every module the target requires is a test case; it fails on a mismatch and
+// is skipped when the reference does not require it and strict mode is off,
+// or when the policy file ignores it.
+// Reference-only modules and directive mismatches are added as failed cases.
+func buildJUnit(report *CheckReport) *junitTestSuites {
+	suites := &junitTestSuites{Name: "gomodsync", Suites: make([]junitTestSuite, 0, len(report.Targets))}
+
+	for _, target := range report.Targets {
+		suite := junitTestSuite{Name: target.Target}
+		if target.Error != "" {
+			suite.Cases = []junitTestCase{{
+				Name:      "go.mod",
+				Classname: target.Target,
+				Error:     &junitProblem{Message: target.Error, Type: RuleTargetError},
+			}}
+		} else {
+			suite.Cases = junitTargetCases(target, report.constraints)
+		}
+
+		for _, tc := range suite.Cases {
+			suite.Tests++
+			switch {
+			case tc.Failure != nil:
+				suite.Failures++
+			case tc.Error != nil:
+				suite.Errors++
+			case tc.Skipped != nil:
+				suite.Skipped++
+			}
+		}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	return suites
+}
+
+// junitTargetCases returns the test cases of a single checked target
+//
+//nolint:gocyclo // One case per kind of mismatch
+func junitTargetCases(target CheckTargetReport, constraints *ConstraintSet) []junitTestCase {
+	result := target.Result
+	refVersions := BuildVersionMap(target.referenceMod)
+	newCase := func(name string) junitTestCase {
+		return junitTestCase{Name: name, Classname: target.Target}
+	}
+
+	mismatches := make(map[string]VersionMismatch, len(result.DependencyMismatches))
+	for _, m := range result.DependencyMismatches {
+		mismatches[m.Module] = m
+	}
+
+	modules := make([]string, 0, len(mismatches))
+	for module := range BuildVersionMap(target.targetMod) {
+		modules = append(modules, module)
+	}
+	for module, m := range mismatches {
+		if m.OnlyInReference {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+
+	cases := make([]junitTestCase, 0, len(modules))
+	for _, module := range modules {
+		tc := newCase(module)
+		if m, exists := mismatches[module]; exists {
+			switch {
+			case m.OnlyInTarget:
+				tc.failure(RuleNotInReference, module+" is not required by the reference", m.TargetVersion, "")
+			case m.OnlyInReference:
+				tc.failure(RuleNotInTarget, module+" is required by the reference but not by the target", "", m.ReferenceVersion)
+			case m.Constraint != "":
+				tc.failure(RuleConstraint, module+" does not satisfy "+m.Constraint, m.TargetVersion, m.Constraint)
+			default:
+				tc.failure(RuleVersionMismatch, module+" does not match the reference", m.TargetVersion, m.ReferenceVersion)
+			}
+		} else if constraint, ok := constraints.Lookup(module); ok {
+			if constraint.Kind == ConstraintIgnore {
+				tc.Skipped = &junitSkipped{Message: "ignored by the policy file"}
+			}
+		} else if _, exists := refVersions[module]; !exists {
+			tc.Skipped = &junitSkipped{Message: "not required by the reference"}
+		}
+		cases = append(cases, tc)
+	}
+
+	if m := result.GoVersionMismatch; m != nil {
+		tc := newCase("go")
+		tc.failure(RuleGoVersion, "go version does not match the reference", m.TargetVersion, m.ReferenceVersion)
+		cases = append(cases, tc)
+	}
+	if m := result.ToolchainMismatch; m != nil {
+		tc := newCase("toolchain")
+		tc.failure(RuleToolchain, "toolchain does not match the reference", m.TargetVersion, m.ReferenceVersion)
+		cases = append(cases, tc)
+	}
+	for _, m := range result.GodebugMismatches {
+		tc := newCase("godebug " + m.Key)
+		tc.failure(RuleGodebug, "godebug "+m.Key+" does not match the reference", m.TargetValue, m.ReferenceValue)
+		cases = append(cases, tc)
+	}
+	for _, tool := range result.MissingTools {
+		tc := newCase("tool " + tool)
+		tc.failure(RuleMissingTool, "tool "+tool+" is not declared by the target", "", tool)
+		cases = append(cases, tc)
+	}
+	for _, m := range result.ReplaceMismatches {
+		replaced := formatReplaced(m.Module, m.ModuleVersion)
+		tc := newCase("replace " + replaced)
+		tc.failure(RuleReplace, "replace "+replaced+" does not match the reference",
+			formatReplacement(m.TargetReplacement, m.TargetReplacementVersion),
+			formatReplacement(m.ReferenceReplacement, m.ReferenceReplacementVersion))
+		cases = append(cases, tc)
+	}
+	for _, e := range result.ExcludedRequirements {
+		tc := newCase("exclude " + e.Module + "@" + e.Version)
+		tc.failure(RuleExcluded, e.Module+" "+e.Version+" is excluded by the reference", e.Version, "exclude "+e.Version)
+		cases = append(cases, tc)
+	}
+
+	return cases
+}
+
+// writeJUnit writes a check report as an indented JUnit XML document
+func writeJUnit(w io.Writer, report *CheckReport) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(buildJUnit(report)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildJUnit(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "svc/go.mod", `module example.com/svc
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.4.0
+	github.com/extra/dep v1.0.0
+)
+`)
+	missing := filepath.Join(dir, "missing", "go.mod")
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.22
+
+require (
+	github.com/pkg/errors v0.9.2
+	golang.org/x/text v0.4.0
+	github.com/google/uuid v1.6.0
+)
+`)
+	require.NoError(t, err)
+
+	t.Run("default mode", func(t *testing.T) {
+		report := RunCheck([]string{target, missing}, "ref/go.mod", referenceMod, CheckOptions{Directives: DefaultDirectives()})
+		suites := buildJUnit(report)
+
+		assert.Equal(t, 5, suites.Tests)
+		assert.Equal(t, 2, suites.Failures)
+		assert.Equal(t, 1, suites.Errors)
+		assert.Equal(t, 1, suites.Skipped)
+		require.Len(t, suites.Suites, 2)
+
+		suite := suites.Suites[0]
+		assert.Equal(t, target, suite.Name)
+		require.Len(t, suite.Cases, 4)
+
+		assert.Equal(t, "github.com/extra/dep", suite.Cases[0].Name)
+		assert.NotNil(t, suite.Cases[0].Skipped)
+
+		errorsCase := suite.Cases[1]
+		assert.Equal(t, "github.com/pkg/errors", errorsCase.Name)
+		assert.Equal(t, target, errorsCase.Classname)
+		require.NotNil(t, errorsCase.Failure)
+		assert.Equal(t, RuleVersionMismatch, errorsCase.Failure.Type)
+		assert.Equal(t, "target: v0.9.1\nreference: v0.9.2", errorsCase.Failure.Text)
+
+		assert.Equal(t, "golang.org/x/text", suite.Cases[2].Name)
+		assert.Nil(t, suite.Cases[2].Failure)
+		assert.Nil(t, suite.Cases[2].Skipped)
+
+		assert.Equal(t, "go", suite.Cases[3].Name)
+		require.NotNil(t, suite.Cases[3].Failure)
+		assert.Equal(t, "target: 1.21\nreference: 1.22", suite.Cases[3].Failure.Text)
+
+		missingSuite := suites.Suites[1]
+		assert.Equal(t, 1, missingSuite.Errors)
+		require.Len(t, missingSuite.Cases, 1)
+		assert.NotNil(t, missingSuite.Cases[0].Error)
+	})
+
+	t.Run("exact mode", func(t *testing.T) {
+		report := RunCheck([]string{target}, "ref/go.mod", referenceMod, CheckOptions{Strict: true, ReverseStrict: true})
+		suites := buildJUnit(report)
+
+		require.Len(t, suites.Suites, 1)
+		suite := suites.Suites[0]
+		assert.Equal(t, 4, suite.Tests)
+		assert.Equal(t, 3, suite.Failures)
+		assert.Equal(t, 0, suite.Skipped)
+
+		byName := make(map[string]junitTestCase)
+		for _, tc := range suite.Cases {
+			byName[tc.Name] = tc
+		}
+		require.NotNil(t, byName["github.com/extra/dep"].Failure)
+		assert.Equal(t, RuleNotInReference, byName["github.com/extra/dep"].Failure.Type)
+		assert.Equal(t, "target: v1.0.0\nreference: (none)", byName["github.com/extra/dep"].Failure.Text)
+		require.NotNil(t, byName["github.com/google/uuid"].Failure)
+		assert.Equal(t, RuleNotInTarget, byName["github.com/google/uuid"].Failure.Type)
+	})
+}
+
+func TestWriteJUnit(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", "module example.com/svc\n\nrequire github.com/pkg/errors v0.9.1\n")
+
+	referenceMod, err := createTestModFile("module example.com/reference\n\nrequire github.com/pkg/errors v0.9.2\n")
+	require.NoError(t, err)
+
+	report := RunCheck([]string{target}, "ref/go.mod", referenceMod, CheckOptions{})
+
+	var buf bytes.Buffer
+	require.NoError(t, writeJUnit(&buf, report))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte(xml.Header)))
+
+	var decoded junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 1, decoded.Tests)
+	assert.Equal(t, 1, decoded.Failures)
+	require.Len(t, decoded.Suites, 1)
+	require.Len(t, decoded.Suites[0].Cases, 1)
+	assert.Equal(t, "github.com/pkg/errors", decoded.Suites[0].Cases[0].Name)
+	assert.Contains(t, decoded.Suites[0].Cases[0].Failure.Text, "reference: v0.9.2")
+}
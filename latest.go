@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// LatestMode selects how far a "latest" reference may move a requirement
+type LatestMode string
+
+// Supported latest modes, used as -reference values
+const (
+	// LatestAny allows any newer release of the module path, including v0 to v1
+	LatestAny LatestMode = "latest"
+	// LatestMinor allows newer minor and patch releases of the current major version
+	LatestMinor LatestMode = "latest-minor"
+	// LatestPatch allows newer patch releases of the current minor version
+	LatestPatch LatestMode = "latest-patch"
+)
+
+// ParseLatestMode reports whether a reference names one of the latest modes
+func ParseLatestMode(reference string) (LatestMode, bool) {
+	switch mode := LatestMode(reference); mode {
+	case LatestAny, LatestMinor, LatestPatch:
+		return mode, true
+	default:
+		return "", false
+	}
+}
+
+// latestReference returns a resolver if the references name a latest mode,
+// or nil if they name reference files. A latest reference cannot be layered.
+func latestReference(references []string) (*LatestResolver, error) {
+	for _, reference := range references {
+		if mode, ok := ParseLatestMode(reference); ok {
+			if len(references) > 1 {
+				return nil, fmt.Errorf("-reference %s cannot be combined with other references", reference)
+			}
+			return NewLatestResolver(mode), nil
+		}
+	}
+	return nil, nil
+}
+
+// LatestResolver builds a reference for a target from the newest versions of
+// its requirements published on the module proxy. Retracted versions and
+// prereleases are never picked.
+type LatestResolver struct {
+	Mode     LatestMode
+	releases map[string][]string // module path -> allowed releases, cached across targets
+}
+
+// NewLatestResolver returns a resolver for the given mode
+func NewLatestResolver(mode LatestMode) *LatestResolver {
+	return &LatestResolver{Mode: mode, releases: make(map[string][]string)}
+}
+
+// Reference returns a reference modfile requiring every module of the target
+// at the newest version the mode allows. A module with no newer release keeps
+// its current version, so requirements are never downgraded.
+func (r *LatestResolver) Reference(targetMod *modfile.File) (*modfile.File, error) {
+	reference, err := ParseGoMod(string(r.Mode), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, req := range targetMod.Require {
+		version, err := r.resolve(req.Mod.Path, req.Mod.Version)
+		if err != nil {
+			return nil, err
+		}
+		reference.AddNewRequire(req.Mod.Path, version, req.Indirect)
+	}
+	return reference, nil
+}
+
+// resolve returns the newest allowed version of a module at current
+func (r *LatestResolver) resolve(modulePath, current string) (string, error) {
+	releases, err := r.moduleReleases(modulePath)
+	if err != nil {
+		return "", err
+	}
+
+	best := current
+	incompatible := strings.HasSuffix(current, "+incompatible")
+	for _, version := range releases {
+		switch {
+		case strings.HasSuffix(version, "+incompatible") && !incompatible:
+			// A +incompatible version is only picked for a module already using one
+			continue
+		case r.Mode == LatestMinor && semver.Major(version) != semver.Major(current):
+			continue
+		case r.Mode == LatestPatch && semver.MajorMinor(version) != semver.MajorMinor(current):
+			continue
+		}
+		if semver.Compare(version, best) > 0 {
+			best = version
+		}
+	}
+	return best, nil
+}
+
+// proxyLatestInfo is the response of the proxy's @latest endpoint
+type proxyLatestInfo struct {
+	Version string
+}
+
+// moduleReleases returns the releases of a module listed by the proxy, minus
+// prereleases and retracted versions. Retractions are read from the go.mod of
+// the newest version, as the go command does.
+func (r *LatestResolver) moduleReleases(modulePath string) ([]string, error) {
+	if releases, exists := r.releases[modulePath]; exists {
+		return releases, nil
+	}
+
+	versions, err := proxyVersions(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of %s: %w", modulePath, err)
+	}
+
+	var releases []string
+	for _, version := range versions {
+		if semver.IsValid(version) && semver.Prerelease(version) == "" {
+			releases = append(releases, version)
+		}
+	}
+
+	if len(releases) > 0 {
+		retractions, err := proxyRetractions(modulePath, newestVersion(versions))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load retractions of %s: %w", modulePath, err)
+		}
+		releases = filterRetracted(releases, retractions)
+	}
+
+	r.releases[modulePath] = releases
+	return releases, nil
+}
+
+// proxyVersions returns the versions of a module from the proxy's @v/list
+// endpoint, plus the version reported by @latest, which also covers proxies
+// that do not list every version. It fails if the proxy has neither.
+func proxyVersions(modulePath string) ([]string, error) {
+	list, listErr := fetchFromProxy(modulePath, "@v/list")
+	if listErr != nil && !isNotFound(listErr) {
+		return nil, listErr
+	}
+	versions := strings.Fields(string(list))
+
+	data, err := fetchFromProxy(modulePath, "@latest")
+	switch {
+	case err != nil && listErr != nil:
+		return nil, err
+	case err == nil:
+		var info proxyLatestInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("invalid @latest response: %w", err)
+		}
+		if info.Version != "" {
+			versions = append(versions, info.Version)
+		}
+	case !isNotFound(err):
+		return nil, err
+	}
+	return versions, nil
+}
+
+// newestVersion returns the newest release among versions, or the newest
+// version if none is a release
+func newestVersion(versions []string) string {
+	var newest, newestRelease string
+	for _, version := range versions {
+		if !semver.IsValid(version) {
+			continue
+		}
+		if semver.Compare(version, newest) > 0 {
+			newest = version
+		}
+		if semver.Prerelease(version) == "" && semver.Compare(version, newestRelease) > 0 {
+			newestRelease = version
+		}
+	}
+	if newestRelease != "" {
+		return newestRelease
+	}
+	return newest
+}
+
+// proxyRetractions returns the retract directives of a module version's go.mod.
+// The file is parsed leniently, as the go command does for dependencies, so
+// directives from newer Go versions do not hide the retractions.
+func proxyRetractions(modulePath, version string) ([]*modfile.Retract, error) {
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetchFromProxy(modulePath, "@v/"+escapedVersion+".mod")
+	if err != nil {
+		return nil, err
+	}
+	mod, err := modfile.ParseLax(modulePath+"@"+version, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return mod.Retract, nil
+}
+
+// filterRetracted drops the versions covered by any retraction
+func filterRetracted(versions []string, retractions []*modfile.Retract) []string {
+	var kept []string
+	for _, version := range versions {
+		retracted := false
+		for _, retraction := range retractions {
+			if semver.Compare(version, retraction.Low) >= 0 && semver.Compare(version, retraction.High) <= 0 {
+				retracted = true
+				break
+			}
+		}
+		if !retracted {
+			kept = append(kept, version)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+const latestTestTarget = `module example.com/svc
+
+go 1.22
+
+require (
+	github.com/foo/a v1.1.0
+	github.com/foo/b v0.3.0
+	github.com/foo/c v0.0.0-20240101000000-abcdefabcdef
+	github.com/foo/d v1.0.0 // indirect
+)
+`
+
+// newLatestTestProxy serves version lists, @latest answers and go.mod files for
+// the modules of latestTestTarget and points GOPROXY at it
+func newLatestTestProxy(t *testing.T) {
+	t.Helper()
+	server := newTestProxy(t, map[string]string{
+		"/github.com/foo/a/@v/list":                    "v1.0.0\nv1.1.0\nv1.1.1\nv1.2.0\nv1.3.0-rc.1\nv1.4.0\n",
+		"/github.com/foo/a/@latest":                    `{"Version":"v1.4.0","Time":"2024-05-01T00:00:00Z"}`,
+		"/github.com/foo/a/@v/v1.4.0.mod":              "module github.com/foo/a\n\nretract v1.2.0 // broken build\n",
+		"/github.com/foo/b/@v/list":                    "v0.3.0\nv0.3.2\nv0.4.0\nv1.0.0\n",
+		"/github.com/foo/b/@v/v1.0.0.mod":              "module github.com/foo/b\n\nretract [v0.3.1, v0.3.2]\n",
+		"/github.com/foo/c/@latest":                    `{"Version":"v0.0.0-20240301000000-123456123456"}`,
+		"/github.com/foo/d/@v/list":                    "v1.0.0\nv1.1.0\nv2.0.0+incompatible\n",
+		"/github.com/foo/d/@v/v2.0.0+incompatible.mod": "module github.com/foo/d\n",
+	})
+	t.Setenv("GOPROXY", server.URL)
+	t.Setenv("GONOPROXY", "")
+}
+
+func TestParseLatestMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected LatestMode
+		ok       bool
+	}{
+		{"latest", LatestAny, true},
+		{"latest-minor", LatestMinor, true},
+		{"latest-patch", LatestPatch, true},
+		{"latest-major", "", false},
+		{"./go.mod", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, ok := ParseLatestMode(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, mode)
+		})
+	}
+}
+
+func TestLatestReference(t *testing.T) {
+	resolver, err := latestReference([]string{"latest-patch"})
+	require.NoError(t, err)
+	require.NotNil(t, resolver)
+	assert.Equal(t, LatestPatch, resolver.Mode)
+
+	resolver, err = latestReference([]string{"./go.mod"})
+	require.NoError(t, err)
+	assert.Nil(t, resolver)
+
+	_, err = latestReference([]string{"./go.mod", "latest"})
+	assert.Error(t, err)
+}
+
+func TestLatestResolver_Reference(t *testing.T) {
+	newLatestTestProxy(t)
+	targetMod, err := createTestModFile(latestTestTarget)
+	require.NoError(t, err)
+
+	tests := []struct {
+		mode     LatestMode
+		expected VersionMap
+	}{
+		{
+			mode: LatestAny,
+			expected: VersionMap{
+				"github.com/foo/a": "v1.4.0",
+				"github.com/foo/b": "v1.0.0",
+				"github.com/foo/c": "v0.0.0-20240101000000-abcdefabcdef",
+				"github.com/foo/d": "v1.1.0",
+			},
+		},
+		{
+			mode: LatestMinor,
+			expected: VersionMap{
+				"github.com/foo/a": "v1.4.0",
+				"github.com/foo/b": "v0.4.0",
+				"github.com/foo/c": "v0.0.0-20240101000000-abcdefabcdef",
+				"github.com/foo/d": "v1.1.0",
+			},
+		},
+		{
+			mode: LatestPatch,
+			expected: VersionMap{
+				"github.com/foo/a": "v1.1.1",
+				"github.com/foo/b": "v0.3.0",
+				"github.com/foo/c": "v0.0.0-20240101000000-abcdefabcdef",
+				"github.com/foo/d": "v1.0.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			reference, err := NewLatestResolver(tt.mode).Reference(targetMod)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, BuildVersionMap(reference))
+		})
+	}
+}
+
+func TestLatestResolver_NeverDowngrades(t *testing.T) {
+	newLatestTestProxy(t)
+	targetMod, err := createTestModFile("module example.com/svc\n\nrequire github.com/foo/a v1.5.0-beta.1\n")
+	require.NoError(t, err)
+
+	reference, err := NewLatestResolver(LatestAny).Reference(targetMod)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.5.0-beta.1", BuildVersionMap(reference)["github.com/foo/a"])
+}
+
+func TestLatestResolver_UnknownModule(t *testing.T) {
+	newLatestTestProxy(t)
+	targetMod, err := createTestModFile("module example.com/svc\n\nrequire github.com/foo/unknown v1.0.0\n")
+	require.NoError(t, err)
+
+	_, err = NewLatestResolver(LatestAny).Reference(targetMod)
+	assert.ErrorContains(t, err, "github.com/foo/unknown")
+}
+
+func TestFilterRetracted(t *testing.T) {
+	mod, err := modfile.Parse("go.mod", []byte("module m\n\nretract (\n\tv1.0.1\n\t[v1.2.0, v1.3.0]\n)\n"), nil)
+	require.NoError(t, err)
+
+	kept := filterRetracted([]string{"v1.0.0", "v1.0.1", "v1.1.0", "v1.2.0", "v1.2.5", "v1.3.0", "v1.3.1"}, mod.Retract)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0", "v1.3.1"}, kept)
+}
+
+func TestProxyRetractions_NewerDirectives(t *testing.T) {
+	server := newTestProxy(t, map[string]string{
+		"/github.com/foo/e/@v/v1.1.0.mod": "module github.com/foo/e\n\ngo 1.99\n\nfuture ./directive\n\nretract v1.0.1\n",
+	})
+	t.Setenv("GOPROXY", server.URL)
+	t.Setenv("GONOPROXY", "")
+
+	retractions, err := proxyRetractions("github.com/foo/e", "v1.1.0")
+	require.NoError(t, err)
+	require.Len(t, retractions, 1)
+	assert.Equal(t, "v1.0.1", retractions[0].Low)
+}
+
+func TestRunSync_Latest(t *testing.T) {
+	newLatestTestProxy(t)
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", latestTestTarget)
+
+	referenceMod, err := loadReference("latest-minor")
+	require.NoError(t, err)
+
+	opts := SyncOptions{Latest: NewLatestResolver(LatestMinor), Prune: true}
+	report := RunSync([]string{target}, "latest-minor", referenceMod, opts, false)
+	require.Empty(t, report.Targets[0].Error)
+
+	// Pruning never removes anything, since every requirement is in the reference
+	result := report.Targets[0].Result
+	assert.Empty(t, result.RemovedModules)
+	assert.Equal(t, []VersionChange{
+		{Module: "github.com/foo/a", OldVersion: "v1.1.0", NewVersion: "v1.4.0"},
+		{Module: "github.com/foo/b", OldVersion: "v0.3.0", NewVersion: "v0.4.0"},
+		{Module: "github.com/foo/d", OldVersion: "v1.0.0", NewVersion: "v1.1.0"},
+	}, result.DependencyChanges)
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "github.com/foo/d v1.1.0 // indirect")
+}
+
+func TestRunCheck_Latest(t *testing.T) {
+	newLatestTestProxy(t)
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", latestTestTarget)
+
+	referenceMod, err := loadReference("latest-patch")
+	require.NoError(t, err)
+
+	report := RunCheck([]string{target}, "latest-patch", referenceMod, CheckOptions{Latest: NewLatestResolver(LatestPatch), Strict: true})
+	require.Len(t, report.Targets, 1)
+	assert.False(t, report.Passed)
+	assert.Equal(t, []VersionMismatch{
+		{Module: "github.com/foo/a", TargetVersion: "v1.1.0", ReferenceVersion: "v1.1.1"},
+	}, report.Targets[0].Result.DependencyMismatches)
+
+	// Up-to-date modules pass in JUnit output rather than being skipped
+	suites := buildJUnit(report)
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 0, suites.Skipped)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// referenceList is a repeatable flag collecting reference layers. Each
+// occurrence may also hold a comma-separated list.
+type referenceList []string
+
+func (l *referenceList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *referenceList) Set(value string) error {
+	*l = append(*l, splitList(value)...)
+	return nil
+}
+
+// ReferenceSources records which reference layer each effective value came from
+type ReferenceSources struct {
+	Modules   map[string]string            // module path -> layer of its version
+	Versions  map[string]map[string]string // module path -> version -> last layer requiring it
+	Go        string                       // layer of the go directive
+	Toolchain string                       // layer of the toolchain directive
+	Godebug   map[string]string            // godebug key -> layer of its value
+	Replaces  map[string]string            // replaced module (path or path@version) -> layer of its replacement
+	Excludes  map[string]string            // excluded path@version -> first layer excluding it
+}
+
+// BuildLayeredReference merges reference layers into a single reference modfile.
+// Layers are applied in order and later layers take precedence: a requirement,
+// go or toolchain directive, godebug setting or replace in a later layer overrides
+// the same one in an earlier layer, regardless of which version is higher. Tool
+// and exclude directives are combined. names holds the display name of each layer.
+func BuildLayeredReference(names []string, layers []*modfile.File) (*modfile.File, *ReferenceSources, error) {
+	reference, err := ParseGoMod(strings.Join(names, ", "), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := &ReferenceSources{
+		Modules:  make(map[string]string),
+		Versions: make(map[string]map[string]string),
+		Godebug:  make(map[string]string),
+		Replaces: make(map[string]string),
+		Excludes: make(map[string]string),
+	}
+	versions := make(VersionMap)
+	indirect := make(map[string]bool)
+	replaces := make(ReplaceMap)
+
+	for i, layer := range layers {
+		name := names[i]
+
+		for _, req := range layer.Require {
+			versions[req.Mod.Path] = req.Mod.Version
+			indirect[req.Mod.Path] = req.Indirect
+			sources.Modules[req.Mod.Path] = name
+			if sources.Versions[req.Mod.Path] == nil {
+				sources.Versions[req.Mod.Path] = make(map[string]string)
+			}
+			sources.Versions[req.Mod.Path][req.Mod.Version] = name
+		}
+		if err := sources.applyLayerDirectives(reference, layer, name); err != nil {
+			return nil, nil, err
+		}
+		for _, rep := range layer.Replace {
+			replaces[rep.Old.String()] = rep
+			sources.Replaces[rep.Old.String()] = name
+		}
+	}
+
+	addRequires(reference, versions, indirect)
+	if err := addReplaces(reference, replaces); err != nil {
+		return nil, nil, err
+	}
+
+	return reference, sources, nil
+}
+
+// applyLayerDirectives applies the go, toolchain, godebug, tool and exclude
+// directives of a layer to the reference and records the layer as their source
+func (s *ReferenceSources) applyLayerDirectives(reference, layer *modfile.File, name string) error {
+	if layer.Go != nil {
+		if err := reference.AddGoStmt(layer.Go.Version); err != nil {
+			return fmt.Errorf("failed to set Go version from %s: %w", name, err)
+		}
+		s.Go = name
+	}
+	if layer.Toolchain != nil {
+		if err := reference.AddToolchainStmt(layer.Toolchain.Name); err != nil {
+			return fmt.Errorf("failed to set toolchain from %s: %w", name, err)
+		}
+		s.Toolchain = name
+	}
+	for _, godebug := range layer.Godebug {
+		if err := reference.AddGodebug(godebug.Key, godebug.Value); err != nil {
+			return fmt.Errorf("failed to add godebug %s from %s: %w", godebug.Key, name, err)
+		}
+		s.Godebug[godebug.Key] = name
+	}
+	for _, tool := range layer.Tool {
+		if err := reference.AddTool(tool.Path); err != nil {
+			return fmt.Errorf("failed to add tool %s from %s: %w", tool.Path, name, err)
+		}
+	}
+	for _, exc := range layer.Exclude {
+		if err := reference.AddExclude(exc.Mod.Path, exc.Mod.Version); err != nil {
+			return fmt.Errorf("failed to add exclude %s from %s: %w", exc.Mod, name, err)
+		}
+		if _, exists := s.Excludes[exc.Mod.String()]; !exists {
+			s.Excludes[exc.Mod.String()] = name
+		}
+	}
+	return nil
+}
+
+// loadReferences loads every reference layer and merges them. A single
+// reference is returned as is, with nil sources, and no reference at all
+// yields an empty modfile.
+func loadReferences(references []string) (*modfile.File, *ReferenceSources, error) {
+	switch len(references) {
+	case 0:
+		referenceMod, err := ParseGoMod("(no reference)", nil)
+		return referenceMod, nil, err
+	case 1:
+		referenceMod, err := loadReference(references[0])
+		return referenceMod, nil, err
+	}
+
+	names := make([]string, 0, len(references))
+	layers := make([]*modfile.File, 0, len(references))
+	for _, reference := range references {
+		layer, err := loadReference(reference)
+		if err != nil {
+			return nil, nil, fmt.Errorf("layer %s: %w", GetReferenceDisplayName(reference), err)
+		}
+		names = append(names, GetReferenceDisplayName(reference))
+		layers = append(layers, layer)
+	}
+
+	return BuildLayeredReference(names, layers)
+}
+
+// referenceDisplayNames returns the display name of one or more reference layers
+func referenceDisplayNames(references []string) string {
+	names := make([]string, 0, len(references))
+	for _, reference := range references {
+		names = append(names, GetReferenceDisplayName(reference))
+	}
+	return strings.Join(names, ", ")
+}
+
+// annotateSyncResult records the reference layer of each version and
+// directive value set by sync.
+// Versions picked by a policy file constraint from the module proxy or lowered
+// to the -go-max cap may not come from any layer.
+func (s *ReferenceSources) annotateSyncResult(result *SyncResult, referenceMod *modfile.File) {
+	if s == nil {
+		return
+	}
+	for i, change := range result.DependencyChanges {
+		result.DependencyChanges[i].Source = s.Versions[change.Module][change.NewVersion]
+	}
+	for i := range result.AddedModules {
+		result.AddedModules[i].Source = s.Modules[result.AddedModules[i].Module]
+	}
+	if change := result.GoVersionChange; change != nil && referenceMod.Go != nil && change.NewVersion == referenceMod.Go.Version {
+		change.Source = s.Go
+	}
+	if change := result.ToolchainChange; change != nil {
+		change.Source = s.Toolchain
+	}
+	for i, change := range result.GodebugChanges {
+		result.GodebugChanges[i].Source = s.Godebug[change.Key]
+	}
+	for i, change := range result.ReplaceChanges {
+		if change.NewReplacement != "" {
+			result.ReplaceChanges[i].Source = s.Replaces[formatReplaced(change.Module, change.ModuleVersion)]
+		}
+	}
+	for i, change := range result.ExcludeChanges {
+		result.ExcludeChanges[i].Source = s.Excludes[change.Module+"@"+change.Version]
+	}
+}
+
+// annotateCheckResult records the reference layer of each expected version and
+// directive value
+func (s *ReferenceSources) annotateCheckResult(result *CheckResult) {
+	if s == nil {
+		return
+	}
+	for i, mismatch := range result.DependencyMismatches {
+		if !mismatch.OnlyInTarget {
+			result.DependencyMismatches[i].Source = s.Versions[mismatch.Module][mismatch.ReferenceVersion]
+		}
+	}
+	if mismatch := result.GoVersionMismatch; mismatch != nil {
+		mismatch.Source = s.Go
+	}
+	if mismatch := result.ToolchainMismatch; mismatch != nil {
+		mismatch.Source = s.Toolchain
+	}
+	for i, mismatch := range result.GodebugMismatches {
+		result.GodebugMismatches[i].Source = s.Godebug[mismatch.Key]
+	}
+	for i, mismatch := range result.ReplaceMismatches {
+		if mismatch.ReferenceReplacement != "" {
+			result.ReplaceMismatches[i].Source = s.Replaces[formatReplaced(mismatch.Module, mismatch.ModuleVersion)]
+		}
+	}
+	for i, excluded := range result.ExcludedRequirements {
+		result.ExcludedRequirements[i].Source = s.Excludes[excluded.Module+"@"+excluded.Version]
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+const (
+	orgLayerContent = `module example.com/org
+
+go 1.21
+
+toolchain go1.21.5
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.14.0
+	github.com/google/uuid v1.6.0 // indirect
+)
+
+tool golang.org/x/tools/cmd/stringer
+
+exclude golang.org/x/crypto v0.1.0
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.2
+`
+	teamLayerContent = `module example.com/team
+
+go 1.22
+
+require (
+	golang.org/x/text v0.15.0
+	github.com/google/uuid v1.6.0
+)
+
+replace github.com/pkg/errors => github.com/team/errors v0.9.3
+`
+	serviceLayerContent = `module example.com/service
+
+require golang.org/x/text v0.13.0
+`
+)
+
+func parseLayers(t *testing.T, contents ...string) []*modfile.File {
+	t.Helper()
+	layers := make([]*modfile.File, 0, len(contents))
+	for _, content := range contents {
+		layer, err := createTestModFile(content)
+		require.NoError(t, err)
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+func TestBuildLayeredReference(t *testing.T) {
+	names := []string{"org.mod", "team.mod", "service.mod"}
+	reference, sources, err := BuildLayeredReference(names, parseLayers(t, orgLayerContent, teamLayerContent, serviceLayerContent))
+	require.NoError(t, err)
+
+	assert.Equal(t, VersionMap{
+		"github.com/pkg/errors":  "v0.9.1",
+		"golang.org/x/text":      "v0.13.0",
+		"github.com/google/uuid": "v1.6.0",
+	}, BuildVersionMap(reference), "later layers win, even with lower versions")
+
+	assert.Equal(t, map[string]string{
+		"github.com/pkg/errors":  "org.mod",
+		"golang.org/x/text":      "service.mod",
+		"github.com/google/uuid": "team.mod",
+	}, sources.Modules)
+
+	require.NotNil(t, reference.Go)
+	assert.Equal(t, "1.22", reference.Go.Version)
+	assert.Equal(t, "team.mod", sources.Go)
+	require.NotNil(t, reference.Toolchain)
+	assert.Equal(t, "go1.21.5", reference.Toolchain.Name)
+
+	for _, req := range reference.Require {
+		if req.Mod.Path == "github.com/google/uuid" {
+			assert.False(t, req.Indirect, "indirect marker comes from the winning layer")
+		}
+	}
+
+	replaces := BuildReplaceMap(reference)
+	require.Contains(t, replaces, "github.com/pkg/errors")
+	assert.Equal(t, "github.com/team/errors", replaces["github.com/pkg/errors"].New.Path)
+
+	require.Len(t, reference.Tool, 1)
+	require.Len(t, reference.Exclude, 1)
+}
+
+func TestReferenceList(t *testing.T) {
+	var references referenceList
+	require.NoError(t, references.Set("org.mod"))
+	require.NoError(t, references.Set("team.mod, service.mod"))
+	assert.Equal(t, referenceList{"org.mod", "team.mod", "service.mod"}, references)
+	assert.Equal(t, "org.mod,team.mod,service.mod", references.String())
+}
+
+func TestLoadReferences(t *testing.T) {
+	dir := t.TempDir()
+	org := writeTestFile(t, dir, "org/go.mod", orgLayerContent)
+	team := writeTestFile(t, dir, "team/go.mod", teamLayerContent)
+
+	t.Run("single reference", func(t *testing.T) {
+		reference, sources, err := loadReferences([]string{org})
+		require.NoError(t, err)
+		assert.Nil(t, sources)
+		assert.Equal(t, "v0.14.0", BuildVersionMap(reference)["golang.org/x/text"])
+	})
+
+	t.Run("layered references", func(t *testing.T) {
+		reference, sources, err := loadReferences([]string{org, team})
+		require.NoError(t, err)
+		require.NotNil(t, sources)
+		assert.Equal(t, "v0.15.0", BuildVersionMap(reference)["golang.org/x/text"])
+		assert.Equal(t, team, sources.Modules["golang.org/x/text"])
+		assert.Equal(t, org+", "+team, referenceDisplayNames([]string{org, team}))
+	})
+
+	t.Run("missing layer", func(t *testing.T) {
+		_, _, err := loadReferences([]string{org, dir + "/missing/go.mod"})
+		assert.Error(t, err)
+	})
+}
+
+func TestLayeredSources(t *testing.T) {
+	names := []string{"org.mod", "team.mod"}
+	reference, sources, err := BuildLayeredReference(names, parseLayers(t, orgLayerContent, teamLayerContent))
+	require.NoError(t, err)
+
+	target := `module example.com/test
+
+go 1.21
+
+require (
+	golang.org/x/text v0.14.0
+	github.com/pkg/errors v0.9.0
+	github.com/extra/dep v1.0.0
+)
+`
+
+	t.Run("sync", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+
+		result, err := SyncVersionsWithOptions(targetMod, reference, SyncOptions{
+			AddMissing: true,
+			Directives: DefaultDirectives(),
+			Sources:    sources,
+		})
+		require.NoError(t, err)
+
+		changes := make(map[string]string)
+		for _, change := range result.DependencyChanges {
+			changes[change.Module] = change.Source
+		}
+		assert.Equal(t, map[string]string{"golang.org/x/text": "team.mod", "github.com/pkg/errors": "org.mod"}, changes)
+		require.Len(t, result.AddedModules, 1)
+		assert.Equal(t, "team.mod", result.AddedModules[0].Source)
+		require.NotNil(t, result.GoVersionChange)
+		assert.Equal(t, "team.mod", result.GoVersionChange.Source)
+	})
+
+	t.Run("sync with go cap", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+
+		result, err := SyncVersionsWithOptions(targetMod, reference, SyncOptions{Directives: DefaultDirectives(), GoMax: "1.21.5", Sources: sources})
+		require.NoError(t, err)
+		require.NotNil(t, result.GoVersionChange)
+		assert.Empty(t, result.GoVersionChange.Source, "a capped version does not come from a layer")
+	})
+
+	t.Run("check", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+
+		result := CheckVersionsWithOptions(targetMod, reference, CheckOptions{Strict: true, Directives: DefaultDirectives(), Sources: sources})
+		sources := make(map[string]string)
+		for _, mismatch := range result.DependencyMismatches {
+			sources[mismatch.Module] = mismatch.Source
+		}
+		assert.Equal(t, map[string]string{
+			"golang.org/x/text":     "team.mod",
+			"github.com/pkg/errors": "org.mod",
+			"github.com/extra/dep":  "",
+		}, sources)
+		require.NotNil(t, result.GoVersionMismatch)
+		assert.Equal(t, "team.mod", result.GoVersionMismatch.Source)
+	})
+
+	t.Run("single reference has no sources", func(t *testing.T) {
+		targetMod, err := createTestModFile(target)
+		require.NoError(t, err)
+
+		result := CheckVersionsWithOptions(targetMod, reference, CheckOptions{Directives: DefaultDirectives()})
+		for _, mismatch := range result.DependencyMismatches {
+			assert.Empty(t, mismatch.Source)
+		}
+	})
+}
+
+func TestLayeredDirectiveSources(t *testing.T) {
+	names := []string{"org.mod", "team.mod", "debug.mod"}
+	reference, sources, err := BuildLayeredReference(names, parseLayers(t, orgLayerContent, teamLayerContent, "module example.com/debug\n\ngodebug panicnil=1\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "org.mod", sources.Toolchain)
+	assert.Equal(t, map[string]string{"panicnil": "debug.mod"}, sources.Godebug)
+	assert.Equal(t, map[string]string{"github.com/pkg/errors": "team.mod"}, sources.Replaces)
+	assert.Equal(t, map[string]string{"golang.org/x/crypto@v0.1.0": "org.mod"}, sources.Excludes)
+
+	directives, err := ParseDirectives("all")
+	require.NoError(t, err)
+
+	t.Run("sync", func(t *testing.T) {
+		targetMod, err := createTestModFile("module example.com/test\n\ngo 1.22\n\ntoolchain go1.20\n\nrequire github.com/pkg/errors v0.9.1\n")
+		require.NoError(t, err)
+
+		result, err := SyncVersionsWithOptions(targetMod, reference, SyncOptions{Directives: directives, Replaces: true, Excludes: true, Sources: sources})
+		require.NoError(t, err)
+		require.NotNil(t, result.ToolchainChange)
+		assert.Equal(t, "org.mod", result.ToolchainChange.Source)
+		require.Len(t, result.GodebugChanges, 1)
+		assert.Equal(t, "debug.mod", result.GodebugChanges[0].Source)
+		require.Len(t, result.ReplaceChanges, 1)
+		assert.Equal(t, "team.mod", result.ReplaceChanges[0].Source)
+		require.Len(t, result.ExcludeChanges, 1)
+		assert.Equal(t, "org.mod", result.ExcludeChanges[0].Source)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		targetMod, err := createTestModFile("module example.com/test\n\ngo 1.22\n\ntoolchain go1.20\n\ngodebug panicnil=0\n\nrequire (\n\tgithub.com/pkg/errors v0.9.1\n\tgolang.org/x/crypto v0.1.0\n)\n")
+		require.NoError(t, err)
+
+		result := CheckVersionsWithOptions(targetMod, reference, CheckOptions{Directives: directives, Replaces: true, Excludes: true, Sources: sources})
+		require.NotNil(t, result.ToolchainMismatch)
+		assert.Equal(t, "org.mod", result.ToolchainMismatch.Source)
+		require.Len(t, result.GodebugMismatches, 1)
+		assert.Equal(t, "debug.mod", result.GodebugMismatches[0].Source)
+		require.Len(t, result.ReplaceMismatches, 1)
+		assert.Equal(t, "team.mod", result.ReplaceMismatches[0].Source)
+		require.Len(t, result.ExcludedRequirements, 1)
+		assert.Equal(t, "org.mod", result.ExcludedRequirements[0].Source)
+	})
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 // Version information (set by goreleaser at build time)
@@ -21,6 +24,15 @@ func main() {
 	command := os.Args[1]
 	args := os.Args[2:]
 
+	// Ctrl-C cancels downloads in progress; a second Ctrl-C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	fetchSettings.Context = ctx
+
 	switch command {
 	case "sync":
 		syncCommand(args)
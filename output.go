@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/mod/modfile"
+)
+
+// OutputFormat selects how sync and check results are printed
+type OutputFormat string
+
+// Supported output formats
+const (
+	FormatText   OutputFormat = "text"
+	FormatJSON   OutputFormat = "json"
+	FormatSARIF  OutputFormat = "sarif"
+	FormatGitHub OutputFormat = "github"
+	FormatJUnit  OutputFormat = "junit"
+)
+
+// ParseOutputFormat validates an output format name against the formats a command supports
+func ParseOutputFormat(name string, supported ...OutputFormat) (OutputFormat, error) {
+	for _, format := range supported {
+		if OutputFormat(name) == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (expected one of %v)", name, supported)
+}
+
+// printSyncChanges prints every change of a sync result, one per line
+func printSyncChanges(result *SyncResult) {
+	if result.GoVersionChange != nil {
+		fmt.Printf("  go: %s -> %s%s\n", result.GoVersionChange.OldVersion, result.GoVersionChange.NewVersion, sourceSuffix(result.GoVersionChange.Source))
+	}
+
+	if result.ToolchainChange != nil {
+		fmt.Printf("  toolchain: %s -> %s%s\n", formatOptional(result.ToolchainChange.OldVersion), result.ToolchainChange.NewVersion, sourceSuffix(result.ToolchainChange.Source))
+	}
+
+	for _, change := range result.GodebugChanges {
+		fmt.Printf("  godebug %s: %s -> %s%s\n", change.Key, formatOptional(change.OldValue), change.NewValue, sourceSuffix(change.Source))
+	}
+
+	for _, tool := range result.AddedTools {
+		fmt.Printf("  tool %s (added)\n", tool)
+	}
+
+	for _, change := range result.DependencyChanges {
+		fmt.Printf("  %s: %s -> %s%s\n", change.Module, change.OldVersion, change.NewVersion, sourceSuffix(change.Source))
+	}
+
+	for _, change := range result.AddedModules {
+		fmt.Printf("  %s: %s (added%s)%s\n", change.Module, change.Version, indirectSuffix(change.Indirect), sourceSuffix(change.Source))
+	}
+
+	for _, change := range result.RemovedModules {
+		fmt.Printf("  %s: %s (removed)\n", change.Module, change.Version)
+	}
+
+	for _, change := range result.ReplaceChanges {
+		fmt.Printf("  replace %s: %s -> %s%s\n",
+			formatReplaced(change.Module, change.ModuleVersion),
+			formatReplacement(change.OldReplacement, change.OldReplacementVersion),
+			formatReplacement(change.NewReplacement, change.NewReplacementVersion),
+			sourceSuffix(change.Source))
+	}
+
+	for _, change := range result.ExcludeChanges {
+		fmt.Printf("  exclude %s %s (added)%s\n", change.Module, change.Version, sourceSuffix(change.Source))
+	}
+}
+
+// printCheckMismatches prints every mismatch of a check result, one per line
+func printCheckMismatches(result *CheckResult) {
+	if result.GoVersionMismatch != nil {
+		fmt.Printf("  go: %s != %s%s\n", result.GoVersionMismatch.TargetVersion, result.GoVersionMismatch.ReferenceVersion, sourceSuffix(result.GoVersionMismatch.Source))
+	}
+
+	if result.ToolchainMismatch != nil {
+		fmt.Printf("  toolchain: %s != %s%s\n", formatOptional(result.ToolchainMismatch.TargetVersion), result.ToolchainMismatch.ReferenceVersion, sourceSuffix(result.ToolchainMismatch.Source))
+	}
+
+	for _, mismatch := range result.GodebugMismatches {
+		fmt.Printf("  godebug %s: %s != %s%s\n", mismatch.Key, formatOptional(mismatch.TargetValue), mismatch.ReferenceValue, sourceSuffix(mismatch.Source))
+	}
+
+	for _, tool := range result.MissingTools {
+		fmt.Printf("  tool %s (not in target)\n", tool)
+	}
+
+	for _, mismatch := range result.DependencyMismatches {
+		switch {
+		case mismatch.OnlyInTarget:
+			fmt.Printf("  %s: %s (not in reference)\n", mismatch.Module, mismatch.TargetVersion)
+		case mismatch.OnlyInReference:
+			fmt.Printf("  %s: %s (not in target)%s\n", mismatch.Module, mismatch.ReferenceVersion, sourceSuffix(mismatch.Source))
+		case mismatch.Constraint == string(ConstraintDeny):
+			fmt.Printf("  %s: %s (denied by policy file)\n", mismatch.Module, mismatch.TargetVersion)
+		case mismatch.Constraint != "":
+			fmt.Printf("  %s: %s does not satisfy %q\n", mismatch.Module, mismatch.TargetVersion, mismatch.Constraint)
+		default:
+			fmt.Printf("  %s: %s != %s%s\n", mismatch.Module, mismatch.TargetVersion, mismatch.ReferenceVersion, sourceSuffix(mismatch.Source))
+		}
+	}
+
+	for _, mismatch := range result.ReplaceMismatches {
+		fmt.Printf("  replace %s: %s != %s%s\n",
+			formatReplaced(mismatch.Module, mismatch.ModuleVersion),
+			formatReplacement(mismatch.TargetReplacement, mismatch.TargetReplacementVersion),
+			formatReplacement(mismatch.ReferenceReplacement, mismatch.ReferenceReplacementVersion),
+			sourceSuffix(mismatch.Source))
+	}
+
+	for _, excluded := range result.ExcludedRequirements {
+		fmt.Printf("  %s: %s (excluded by reference)%s\n", excluded.Module, excluded.Version, sourceSuffix(excluded.Source))
+	}
+}
+
+// indirectSuffix returns the display marker for indirect requirements
+func indirectSuffix(indirect bool) string {
+	if indirect {
+		return ", indirect"
+	}
+	return ""
+}
+
+// sourceSuffix returns the display marker for the reference layer a version came from
+func sourceSuffix(source string) string {
+	if source == "" {
+		return ""
+	}
+	return " (from " + source + ")"
+}
+
+// formatOptional returns a display form of a value that may be unset
+func formatOptional(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// printPreview prints the formatted target file for dry-run previews
+func printPreview(targetMod *modfile.File) {
+	fmt.Println("\nPreview of updated go.mod:")
+	fmt.Println("---")
+	previewData, err := targetMod.Format()
+	if err != nil {
+		log.Fatalf("Failed to format target file: %v", err)
+	}
+	fmt.Println(string(previewData))
+}
+
+// printSyncText prints a sync report as human-readable text. A single
+// target keeps the compact report; several targets get one line each and
+// a combined summary. With showDiff, each changed file is shown as a
+// unified diff instead of a list of changes and a preview. It exits if a
+// single target could not be synced.
+func printSyncText(report *SyncReport, single, verbose, showDiff bool) {
+	if single {
+		printSyncSingle(report.Targets[0], report.DryRun, verbose, showDiff)
+		return
+	}
+
+	for _, target := range report.Targets {
+		if target.Error != "" {
+			fmt.Printf("✗ %s: %s\n", target.Target, target.Error)
+			continue
+		}
+
+		if target.Changes == 0 {
+			if verbose {
+				fmt.Printf("✓ %s: already in sync\n", target.Target)
+			}
+			continue
+		}
+
+		fmt.Printf("• %s: %d change(s)\n", target.Target, target.Changes)
+		if showDiff {
+			fmt.Print(target.diff)
+		} else if verbose {
+			printSyncChanges(target.Result)
+			if report.DryRun {
+				printPreview(target.targetMod)
+			}
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case report.DryRun:
+		fmt.Printf("Dry-run mode: %d change(s) identified in %d of %d go.mod file(s) but not applied.\n",
+			report.TotalChanges, report.ChangedFiles, len(report.Targets))
+	case report.TotalChanges == 0:
+		fmt.Printf("✓ No version differences found in %d go.mod file(s).\n", len(report.Targets))
+	default:
+		fmt.Printf("✓ Successfully updated %d of %d go.mod file(s) (%d change(s) applied)\n",
+			report.ChangedFiles, len(report.Targets), report.TotalChanges)
+	}
+
+	if report.FailedFiles > 0 {
+		fmt.Printf("✗ %d go.mod file(s) could not be synced\n", report.FailedFiles)
+	}
+}
+
+// printSyncSingle prints the report of a single -target file
+func printSyncSingle(target SyncTargetReport, dryRun, verbose, showDiff bool) {
+	if target.Error != "" {
+		log.Fatalf("Failed to sync %s: %s", target.Target, target.Error)
+	}
+
+	if target.Changes == 0 {
+		fmt.Println("✓ No version differences found. Target file is already in sync.")
+		return
+	}
+
+	// Print the diff, or the changes if verbose
+	if showDiff {
+		fmt.Println(target.diff)
+	} else if verbose {
+		fmt.Printf("Changes to be made:\n\n")
+		printSyncChanges(target.Result)
+		fmt.Println()
+	}
+
+	if dryRun {
+		fmt.Printf("Dry-run mode: %d change(s) identified but not applied.\n", target.Changes)
+		if verbose && !showDiff {
+			printPreview(target.targetMod)
+		}
+		return
+	}
+
+	fmt.Printf("✓ Successfully updated %s (%d change(s) applied)\n", target.Target, target.Changes)
+}
+
+// printCheckText prints a check report as human-readable text. A single
+// target keeps the compact report; several targets get one line each and
+// a combined summary. It exits if a single target could not be read.
+func printCheckText(report *CheckReport, single, verbose bool) {
+	if single {
+		printCheckSingle(report.Targets[0], verbose)
+		return
+	}
+
+	for _, target := range report.Targets {
+		if target.Error != "" {
+			fmt.Printf("✗ %s: %s\n", target.Target, target.Error)
+			continue
+		}
+
+		if target.Mismatches == 0 {
+			if verbose {
+				fmt.Printf("✓ %s\n", target.Target)
+			}
+			continue
+		}
+
+		fmt.Printf("✗ %s: %d mismatch(es)\n", target.Target, target.Mismatches)
+		if verbose {
+			printCheckMismatches(target.Result)
+		}
+	}
+
+	fmt.Println()
+	if report.Passed {
+		fmt.Printf("✓ All versions match in %d go.mod file(s)!\n", len(report.Targets))
+		return
+	}
+
+	fmt.Printf("✗ Version check failed: %d mismatch(es) found in %d of %d go.mod file(s)\n",
+		report.TotalMismatches, report.FailedFiles, len(report.Targets))
+	if !verbose {
+		fmt.Println("Run with -verbose to see details")
+	}
+}
+
+// printCheckSingle prints the report of a single -target file
+func printCheckSingle(target CheckTargetReport, verbose bool) {
+	if target.Error != "" {
+		log.Fatalf("Failed to check %s: %s", target.Target, target.Error)
+	}
+
+	if target.Mismatches == 0 {
+		fmt.Println("✓ All versions match (dependencies and Go version)!")
+		return
+	}
+
+	// Print summary or detailed mismatches based on verbose flag
+	if verbose {
+		fmt.Printf("✗ Found %d version mismatch(es):\n\n", target.Mismatches)
+		printCheckMismatches(target.Result)
+	} else {
+		fmt.Printf("✗ Version check failed: %d mismatch(es) found\n", target.Mismatches)
+		fmt.Println("Run with -verbose to see details")
+	}
+}
+
+// writeReport writes a report to stdout in a machine-readable format
+func writeReport(report any) {
+	if err := writeJSON(os.Stdout, report); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
+
+// writeCheckReport writes a check report to stdout in a machine-readable format
+func writeCheckReport(report *CheckReport, format OutputFormat) {
+	var err error
+	switch format {
+	case FormatJUnit:
+		err = writeJUnit(os.Stdout, report)
+	case FormatSARIF:
+		err = writeSARIF(os.Stdout, CheckAnnotations(report))
+	case FormatGitHub:
+		err = writeGitHubAnnotations(os.Stdout, CheckAnnotations(report))
+	default:
+		err = writeJSON(os.Stdout, report)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
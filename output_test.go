@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	format, err := ParseOutputFormat("json", FormatText, FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	format, err = ParseOutputFormat("text", FormatText, FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+
+	_, err = ParseOutputFormat("yaml", FormatText, FormatJSON)
+	assert.Error(t, err)
+}
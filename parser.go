@@ -1,6 +1,11 @@
 package main
 
-import "golang.org/x/mod/modfile"
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/mod/modfile"
+)
 
 // ParseGoMod reads and parses a go.mod file
 func ParseGoMod(filename string, data []byte) (*modfile.File, error) {
@@ -15,3 +20,55 @@ func BuildVersionMap(mod *modfile.File) VersionMap {
 	}
 	return versions
 }
+
+// BuildReplaceMap creates a map of replaced modules to their replace directives
+// from a modfile. Keys are the module path, or path@version for replaces that
+// only apply to one version.
+func BuildReplaceMap(mod *modfile.File) ReplaceMap {
+	replaces := make(ReplaceMap)
+	for _, rep := range mod.Replace {
+		replaces[rep.Old.String()] = rep
+	}
+	return replaces
+}
+
+// addRequires adds a requirement on each module of versions to a reference
+// modfile, sorted by module path. indirect marks the indirect requirements and
+// may be nil.
+func addRequires(reference *modfile.File, versions VersionMap, indirect map[string]bool) {
+	modules := make([]string, 0, len(versions))
+	for module := range versions {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		reference.AddNewRequire(module, versions[module], indirect[module])
+	}
+}
+
+// addReplaces adds the replace directives of replaces to a reference modfile,
+// sorted by replaced module
+func addReplaces(reference *modfile.File, replaces ReplaceMap) error {
+	keys := make([]string, 0, len(replaces))
+	for key := range replaces {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rep := replaces[key]
+		if err := reference.AddReplace(rep.Old.Path, rep.Old.Version, rep.New.Path, rep.New.Version); err != nil {
+			return fmt.Errorf("failed to add replace %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ParseLocalReplacePolicy validates a local replace policy name
+func ParseLocalReplacePolicy(name string) (LocalReplacePolicy, error) {
+	switch policy := LocalReplacePolicy(name); policy {
+	case LocalReplacesKeep, LocalReplacesSync:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown local replace policy %q (expected %s or %s)", name, LocalReplacesKeep, LocalReplacesSync)
+	}
+}
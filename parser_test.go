@@ -130,3 +130,16 @@ replace package/something => ../../../package/src/go
 		})
 	}
 }
+
+func TestParseLocalReplacePolicy(t *testing.T) {
+	policy, err := ParseLocalReplacePolicy("keep")
+	assert.NoError(t, err)
+	assert.Equal(t, LocalReplacesKeep, policy)
+
+	policy, err = ParseLocalReplacePolicy("sync")
+	assert.NoError(t, err)
+	assert.Equal(t, LocalReplacesSync, policy)
+
+	_, err = ParseLocalReplacePolicy("drop")
+	assert.Error(t, err)
+}
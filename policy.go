@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	gover "go/version"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionPolicy decides how a target version is compared with the reference version
+type VersionPolicy string
+
+// Supported version policies. The zero value behaves like PolicyExact.
+const (
+	// PolicyExact requires the target version to equal the reference version
+	PolicyExact VersionPolicy = "exact"
+	// PolicyUpgradeOnly never downgrades on sync, but check still reports targets newer than the reference
+	PolicyUpgradeOnly VersionPolicy = "upgrade-only"
+	// PolicyAllowAhead accepts targets newer than the reference: sync never downgrades them and check passes them
+	PolicyAllowAhead VersionPolicy = "allow-ahead"
+	// PolicySameMajor only compares versions that share a major version, so sync never crosses majors
+	PolicySameMajor VersionPolicy = "same-major"
+)
+
+// ParseVersionPolicy validates a version policy name
+func ParseVersionPolicy(name string) (VersionPolicy, error) {
+	switch policy := VersionPolicy(name); policy {
+	case PolicyExact, PolicyUpgradeOnly, PolicyAllowAhead, PolicySameMajor:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown version policy %q (expected %s, %s, %s or %s)",
+			name, PolicyExact, PolicyUpgradeOnly, PolicyAllowAhead, PolicySameMajor)
+	}
+}
+
+// ShouldUpdate reports whether the target version must change to the reference
+// version under the policy. Versions are compared with semver ordering, which
+// handles pseudo-versions and ignores +incompatible build metadata. If either
+// version is not valid semver, any difference counts, as with PolicyExact.
+func (p VersionPolicy) ShouldUpdate(targetVersion, refVersion string) bool {
+	if targetVersion == refVersion {
+		return false
+	}
+	if !semver.IsValid(targetVersion) || !semver.IsValid(refVersion) {
+		return true
+	}
+
+	switch p {
+	case PolicyUpgradeOnly, PolicyAllowAhead:
+		return semver.Compare(targetVersion, refVersion) < 0
+	case PolicySameMajor:
+		return semver.Major(targetVersion) == semver.Major(refVersion) && semver.Compare(targetVersion, refVersion) != 0
+	default:
+		return true
+	}
+}
+
+// ShouldReport reports whether check counts the target version as a mismatch
+// under the policy. It matches ShouldUpdate, except that PolicyUpgradeOnly
+// still reports targets ahead of the reference, which sync leaves alone.
+func (p VersionPolicy) ShouldReport(targetVersion, refVersion string) bool {
+	if p == PolicyUpgradeOnly {
+		return PolicyExact.ShouldUpdate(targetVersion, refVersion)
+	}
+	return p.ShouldUpdate(targetVersion, refVersion)
+}
+
+// GoVersionPolicy decides how the target go directive is compared with the reference
+type GoVersionPolicy string
+
+// Supported go directive policies. The zero value behaves like GoPolicyExact.
+const (
+	// GoPolicyExact requires the target go version to equal the reference go version
+	GoPolicyExact GoVersionPolicy = "exact"
+	// GoPolicyAtLeastReference accepts a target go version at or above the reference
+	GoPolicyAtLeastReference GoVersionPolicy = "at-least-reference"
+	// GoPolicyAtMostReference accepts a target go version at or below the reference
+	GoPolicyAtMostReference GoVersionPolicy = "at-most-reference"
+)
+
+// ParseGoVersionPolicy validates a go directive policy name
+func ParseGoVersionPolicy(name string) (GoVersionPolicy, error) {
+	switch policy := GoVersionPolicy(name); policy {
+	case GoPolicyExact, GoPolicyAtLeastReference, GoPolicyAtMostReference:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown go version policy %q (expected %s, %s or %s)",
+			name, GoPolicyExact, GoPolicyAtLeastReference, GoPolicyAtMostReference)
+	}
+}
+
+// ShouldUpdate reports whether the target go version must change to the reference
+// go version under the policy. A missing target version always needs updating.
+func (p GoVersionPolicy) ShouldUpdate(targetVersion, refVersion string) bool {
+	if targetVersion == "" {
+		return true
+	}
+
+	cmp, ok := CompareGoVersions(targetVersion, refVersion)
+	if !ok {
+		return targetVersion != refVersion
+	}
+
+	switch p {
+	case GoPolicyAtLeastReference:
+		return cmp < 0
+	case GoPolicyAtMostReference:
+		return cmp > 0
+	default:
+		return cmp != 0
+	}
+}
+
+// CompareGoVersions compares two go directive versions such as 1.21, 1.21.0 or
+// 1.22rc1 using toolchain ordering. A bare language version is treated as its
+// first release, so 1.21 equals 1.21.0. ok is false if either version is invalid.
+func CompareGoVersions(a, b string) (cmp int, ok bool) {
+	a, b = normalizeGoVersion(a), normalizeGoVersion(b)
+	if !gover.IsValid(a) || !gover.IsValid(b) {
+		return 0, false
+	}
+	return gover.Compare(a, b), true
+}
+
+// normalizeGoVersion converts a go directive version to a go/version string
+func normalizeGoVersion(v string) string {
+	v = "go" + v
+	if gover.IsValid(v) && gover.Lang(v) == v {
+		v += ".0"
+	}
+	return v
+}
+
+// ValidateGoVersion checks that v is a valid go directive version
+func ValidateGoVersion(v string) error {
+	if !gover.IsValid(normalizeGoVersion(v)) {
+		return fmt.Errorf("invalid go version %q", v)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionPolicy(t *testing.T) {
+	for _, name := range []string{"exact", "upgrade-only", "allow-ahead", "same-major"} {
+		policy, err := ParseVersionPolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, VersionPolicy(name), policy)
+	}
+
+	_, err := ParseVersionPolicy("newest")
+	assert.Error(t, err)
+}
+
+func TestVersionPolicyShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        VersionPolicy
+		targetVersion string
+		refVersion    string
+		expected      bool
+	}{
+		{"exact equal", PolicyExact, "v1.2.0", "v1.2.0", false},
+		{"exact behind", PolicyExact, "v1.2.0", "v1.3.0", true},
+		{"exact ahead", PolicyExact, "v1.4.0", "v1.3.0", true},
+		{"zero value behaves like exact", "", "v1.4.0", "v1.3.0", true},
+
+		{"upgrade-only behind", PolicyUpgradeOnly, "v1.2.0", "v1.3.0", true},
+		{"upgrade-only ahead", PolicyUpgradeOnly, "v1.4.0", "v1.3.0", false},
+		{"upgrade-only numeric ordering", PolicyUpgradeOnly, "v1.10.0", "v1.9.0", false},
+		{"upgrade-only pseudo-version behind release", PolicyUpgradeOnly, "v1.2.1-0.20230101000000-abcdefabcdef", "v1.2.1", true},
+		{"upgrade-only pseudo-version ahead of release", PolicyUpgradeOnly, "v1.2.1-0.20230101000000-abcdefabcdef", "v1.2.0", false},
+		{"upgrade-only incompatible", PolicyUpgradeOnly, "v2.0.0+incompatible", "v2.1.0+incompatible", true},
+		{"allow-ahead ahead", PolicyAllowAhead, "v1.4.0", "v1.3.0", false},
+		{"allow-ahead behind", PolicyAllowAhead, "v1.2.0", "v1.3.0", true},
+
+		{"same-major downgrade", PolicySameMajor, "v1.4.0", "v1.3.0", true},
+		{"same-major upgrade", PolicySameMajor, "v1.2.0", "v1.3.0", true},
+		{"same-major crosses major", PolicySameMajor, "v1.2.0", "v2.0.0+incompatible", false},
+		{"same-major v0 to v1", PolicySameMajor, "v0.9.0", "v1.0.0", false},
+
+		{"invalid version falls back to exact", PolicyUpgradeOnly, "latest", "v1.0.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.ShouldUpdate(tt.targetVersion, tt.refVersion))
+		})
+	}
+}
+
+func TestVersionPolicyShouldReport(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        VersionPolicy
+		targetVersion string
+		refVersion    string
+		expected      bool
+	}{
+		{"exact ahead", PolicyExact, "v1.4.0", "v1.3.0", true},
+		{"upgrade-only behind", PolicyUpgradeOnly, "v1.2.0", "v1.3.0", true},
+		{"upgrade-only ahead", PolicyUpgradeOnly, "v1.4.0", "v1.3.0", true},
+		{"upgrade-only equal", PolicyUpgradeOnly, "v1.3.0", "v1.3.0", false},
+		{"allow-ahead behind", PolicyAllowAhead, "v1.2.0", "v1.3.0", true},
+		{"allow-ahead ahead", PolicyAllowAhead, "v1.4.0", "v1.3.0", false},
+		{"same-major crosses major", PolicySameMajor, "v1.2.0", "v2.0.0+incompatible", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.ShouldReport(tt.targetVersion, tt.refVersion))
+		})
+	}
+}
+
+func TestCompareVersionsWithPolicy(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.5.0
+)`)
+	require.NoError(t, err)
+
+	refVersions := VersionMap{
+		"github.com/pkg/errors": "v0.9.2",
+		"golang.org/x/text":     "v0.4.0",
+	}
+
+	assert.Len(t, CompareVersionsWithPolicy(targetMod, refVersions, PolicyExact), 2)
+	assert.Equal(t, []VersionChange{
+		{Module: "github.com/pkg/errors", OldVersion: "v0.9.1", NewVersion: "v0.9.2"},
+	}, CompareVersionsWithPolicy(targetMod, refVersions, PolicyUpgradeOnly))
+}
+
+func TestParseGoVersionPolicy(t *testing.T) {
+	for _, name := range []string{"exact", "at-least-reference", "at-most-reference"} {
+		policy, err := ParseGoVersionPolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, GoVersionPolicy(name), policy)
+	}
+
+	_, err := ParseGoVersionPolicy("newest")
+	assert.Error(t, err)
+}
+
+func TestCompareGoVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+		ok       bool
+	}{
+		{"1.21", "1.21.0", 0, true},
+		{"1.21.0", "1.21", 0, true},
+		{"1.21.1", "1.21", 1, true},
+		{"1.21rc1", "1.21.0", -1, true},
+		{"1.9", "1.10", -1, true},
+		{"1.23", "1.22.5", 1, true},
+		{"1.22", "latest", 0, false},
+		{"", "1.22", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			cmp, ok := CompareGoVersions(tt.a, tt.b)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, cmp)
+		})
+	}
+}
+
+func TestGoVersionPolicyShouldUpdate(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        GoVersionPolicy
+		targetVersion string
+		refVersion    string
+		expected      bool
+	}{
+		{"exact equal", GoPolicyExact, "1.22", "1.22", false},
+		{"exact equal with patch", GoPolicyExact, "1.21", "1.21.0", false},
+		{"exact behind", GoPolicyExact, "1.21", "1.22", true},
+		{"exact ahead", GoPolicyExact, "1.23", "1.22", true},
+		{"zero value behaves like exact", "", "1.23", "1.22", true},
+		{"missing target", GoPolicyAtLeastReference, "", "1.22", true},
+
+		{"at-least-reference behind", GoPolicyAtLeastReference, "1.21.5", "1.22", true},
+		{"at-least-reference ahead", GoPolicyAtLeastReference, "1.23", "1.22", false},
+		{"at-least-reference numeric ordering", GoPolicyAtLeastReference, "1.10", "1.9", false},
+
+		{"at-most-reference ahead", GoPolicyAtMostReference, "1.23", "1.22", true},
+		{"at-most-reference behind", GoPolicyAtMostReference, "1.21", "1.22", false},
+
+		{"invalid version falls back to string equality", GoPolicyAtLeastReference, "1.x", "1.22", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.policy.ShouldUpdate(tt.targetVersion, tt.refVersion))
+		})
+	}
+}
+
+func TestValidateGoVersion(t *testing.T) {
+	assert.NoError(t, ValidateGoVersion("1.22"))
+	assert.NoError(t, ValidateGoVersion("1.22.3"))
+	assert.Error(t, ValidateGoVersion("go1.22"))
+	assert.Error(t, ValidateGoVersion("latest"))
+}
+
+func TestSyncVersionsWithOptions_GoVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		targetGo   string
+		refGo      string
+		policy     GoVersionPolicy
+		goMax      string
+		expectedGo string
+		changed    bool
+	}{
+		{"equivalent versions are not changed", "1.21", "1.21.0", GoPolicyExact, "", "1.21", false},
+		{"exact lowers the target", "1.23", "1.22", GoPolicyExact, "", "1.22", true},
+		{"at-least-reference keeps a newer target", "1.23", "1.22", GoPolicyAtLeastReference, "", "1.23", false},
+		{"at-least-reference raises an older target", "1.21", "1.22", GoPolicyAtLeastReference, "", "1.22", true},
+		{"at-most-reference lowers a newer target", "1.23", "1.22", GoPolicyAtMostReference, "", "1.22", true},
+		{"cap below reference raises to the cap", "1.21", "1.24", GoPolicyExact, "1.22", "1.22", true},
+		{"cap never lowers the target", "1.23", "1.24", GoPolicyExact, "1.22", "1.23", false},
+		{"reference within the cap", "1.21", "1.22", GoPolicyExact, "1.22.5", "1.22", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetMod, err := createTestModFile("module example.com/test\n\ngo " + tt.targetGo + "\n")
+			require.NoError(t, err)
+			referenceMod, err := createTestModFile("module example.com/reference\n\ngo " + tt.refGo + "\n")
+			require.NoError(t, err)
+
+			result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{
+				GoPolicy:   tt.policy,
+				GoMax:      tt.goMax,
+				Directives: DefaultDirectives(),
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedGo, targetMod.Go.Version)
+			assert.Equal(t, tt.changed, result.GoVersionChange != nil)
+		})
+	}
+}
+
+func TestCheckVersionsWithOptions_GoPolicy(t *testing.T) {
+	targetMod, err := createTestModFile("module example.com/test\n\ngo 1.23\n")
+	require.NoError(t, err)
+	referenceMod, err := createTestModFile("module example.com/reference\n\ngo 1.22.0\n")
+	require.NoError(t, err)
+
+	result := CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{GoPolicy: GoPolicyAtLeastReference, Directives: DefaultDirectives()})
+	assert.Nil(t, result.GoVersionMismatch)
+
+	result = CheckVersionsWithOptions(targetMod, referenceMod, CheckOptions{GoPolicy: GoPolicyAtMostReference, Directives: DefaultDirectives()})
+	require.NotNil(t, result.GoVersionMismatch)
+	assert.Equal(t, "1.23", result.GoVersionMismatch.TargetVersion)
+	assert.Equal(t, "1.22.0", result.GoVersionMismatch.ReferenceVersion)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// proxyPrefix marks a reference resolved through the module proxy
+const proxyPrefix = "proxy:"
+
+// defaultGOPROXY is used when GOPROXY is unset, matching the go command
+const defaultGOPROXY = "https://proxy.golang.org,direct"
+
+// isProxyReference checks if the reference names a module version on the proxy
+func isProxyReference(reference string) bool {
+	return strings.HasPrefix(reference, proxyPrefix)
+}
+
+// parseProxyReference splits a proxy:module@version reference into its module
+// path and version
+func parseProxyReference(reference string) (modulePath, version string, err error) {
+	spec := strings.TrimPrefix(reference, proxyPrefix)
+	modulePath, version, found := strings.Cut(spec, "@")
+	if !found || modulePath == "" || version == "" {
+		return "", "", fmt.Errorf("invalid proxy reference %q: expected proxy:module@version", reference)
+	}
+	if err := module.Check(modulePath, version); err != nil {
+		return "", "", fmt.Errorf("invalid proxy reference %q: %w", reference, err)
+	}
+	if module.CanonicalVersion(version) != version {
+		return "", "", fmt.Errorf("invalid proxy reference %q: version must be canonical, e.g. %s", reference, module.CanonicalVersion(version))
+	}
+	return modulePath, version, nil
+}
+
+// proxyEntry is one element of the GOPROXY list
+type proxyEntry struct {
+	url string // proxy base URL, "direct" or "off"
+	// fallbackOnError is set when the entry is followed by "|": any error moves
+	// on to the next entry, not only a 404 or 410 response
+	fallbackOnError bool
+}
+
+// parseProxyList parses a GOPROXY value. Entries are separated by commas or
+// pipes, and entries after "direct" or "off" are never consulted.
+func parseProxyList(value string) ([]proxyEntry, error) {
+	var entries []proxyEntry
+	for value != "" {
+		var entry proxyEntry
+		if i := strings.IndexAny(value, ",|"); i >= 0 {
+			entry.url = strings.TrimSpace(value[:i])
+			entry.fallbackOnError = value[i] == '|'
+			value = value[i+1:]
+		} else {
+			entry.url = strings.TrimSpace(value)
+			value = ""
+		}
+
+		if entry.url == "" {
+			continue
+		}
+		entries = append(entries, entry)
+		if entry.url == "direct" || entry.url == "off" {
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("GOPROXY list is empty")
+	}
+	return entries, nil
+}
+
+// proxyEntries returns the proxies to consult for a module. Modules matching
+// GONOPROXY (or GOPRIVATE when GONOPROXY is unset) are fetched directly from
+// their repository.
+func proxyEntries(modulePath string) ([]proxyEntry, error) {
+	noProxy, set := os.LookupEnv("GONOPROXY")
+	if !set {
+		noProxy = os.Getenv("GOPRIVATE")
+	}
+	if module.MatchPrefixPatterns(noProxy, modulePath) {
+		return []proxyEntry{{url: "direct"}}, nil
+	}
+
+	value := os.Getenv("GOPROXY")
+	if value == "" {
+		value = defaultGOPROXY
+	}
+	return parseProxyList(value)
+}
+
+// fetchProxyReference fetches the go.mod file of a proxy:module@version reference
+func fetchProxyReference(reference string) ([]byte, error) {
+	modulePath, version, err := parseProxyReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return fetchFromProxy(modulePath, "@v/"+escapedVersion+".mod")
+}
+
+// fetchFromProxy fetches a file of a module, such as "@v/list", walking the
+// GOPROXY list. The next proxy is tried after a 404 or 410 response, or after
+// any error when the entries are separated by "|". "direct" fetches from the
+// module's git repository.
+func fetchFromProxy(modulePath, file string) ([]byte, error) {
+	entries, err := proxyEntries(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, entry := range entries {
+		switch entry.url {
+		case "off":
+			return nil, fmt.Errorf("%s: module lookup disabled by GOPROXY=off", modulePath)
+		case "direct":
+			data, err := fetchDirect(modulePath, file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", modulePath, err)
+			}
+			return data, nil
+		}
+
+		data, err := fetchProxyFile(entry.url, escapedPath+"/"+file)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = fmt.Errorf("%s: %w", modulePath, err)
+		if !entry.fallbackOnError && !isNotFound(err) {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// fetchProxyFile fetches a file relative to a proxy base URL. file:// proxies
+// are read from the local file system.
+func fetchProxyFile(base, file string) ([]byte, error) {
+	if !strings.HasPrefix(base, "file://") {
+		return fetchFromURL(strings.TrimSuffix(base, "/") + "/" + file)
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOPROXY URL %q: %w", base, err)
+	}
+	return os.ReadFile(filepath.Join(filepath.FromSlash(u.Path), filepath.FromSlash(file)))
+}
+
+// isNotFound reports whether a proxy does not have the requested file
+func isNotFound(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone
+	}
+	return errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const proxyTestMod = "module github.com/org/platform\n\ngo 1.22\n\nrequire github.com/pkg/errors v0.9.1\n"
+
+// newTestProxy serves the given files, keyed by request path, and 404 for anything else
+func newTestProxy(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, exists := files[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newStatusProxy answers every request with the given status code
+func newStatusProxy(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseProxyReference(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantModule  string
+		wantVersion string
+		expectError bool
+	}{
+		{"module and version", "proxy:github.com/org/platform@v1.42.0", "github.com/org/platform", "v1.42.0", false},
+		{"major version suffix", "proxy:github.com/org/platform/v2@v2.1.0", "github.com/org/platform/v2", "v2.1.0", false},
+		{"missing version", "proxy:github.com/org/platform", "", "", true},
+		{"empty version", "proxy:github.com/org/platform@", "", "", true},
+		{"non-canonical version", "proxy:github.com/org/platform@v1.42", "", "", true},
+		{"major version mismatch", "proxy:github.com/org/platform@v2.0.0", "", "", true},
+		{"invalid module path", "proxy:not a module@v1.0.0", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modulePath, version, err := parseProxyReference(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantModule, modulePath)
+			assert.Equal(t, tt.wantVersion, version)
+		})
+	}
+}
+
+func TestParseProxyList(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []proxyEntry
+		expectError bool
+	}{
+		{
+			name:  "default",
+			input: defaultGOPROXY,
+			expected: []proxyEntry{
+				{url: "https://proxy.golang.org"},
+				{url: "direct"},
+			},
+		},
+		{
+			name:  "pipe separator",
+			input: "https://a.example|https://b.example,off",
+			expected: []proxyEntry{
+				{url: "https://a.example", fallbackOnError: true},
+				{url: "https://b.example"},
+				{url: "off"},
+			},
+		},
+		{
+			name:     "entries after direct are ignored",
+			input:    "direct,https://a.example",
+			expected: []proxyEntry{{url: "direct"}},
+		},
+		{
+			name:     "empty entries are skipped",
+			input:    ",https://a.example,,",
+			expected: []proxyEntry{{url: "https://a.example"}},
+		},
+		{"only separators", ",|,", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseProxyList(tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, entries)
+		})
+	}
+}
+
+func TestFetchReference_Proxy(t *testing.T) {
+	const modPath = "/github.com/org/platform/@v/v1.42.0.mod"
+	const reference = "proxy:github.com/org/platform@v1.42.0"
+
+	good := newTestProxy(t, map[string]string{modPath: proxyTestMod})
+	missing := newTestProxy(t, nil)
+	gone := newStatusProxy(t, http.StatusGone)
+	broken := newStatusProxy(t, http.StatusInternalServerError)
+
+	tests := []struct {
+		name        string
+		goproxy     string
+		expectError bool
+	}{
+		{"single proxy", good.URL, false},
+		{"trailing slash", good.URL + "/", false},
+		{"comma falls back after 404", missing.URL + "," + good.URL, false},
+		{"comma falls back after 410", gone.URL + "," + good.URL, false},
+		{"comma stops after 500", broken.URL + "," + good.URL, true},
+		{"pipe falls back after 500", broken.URL + "|" + good.URL, false},
+		{"not found anywhere", missing.URL + "," + gone.URL, true},
+		{"off", "off", true},
+		{"off after a miss", missing.URL + ",off", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOPROXY", tt.goproxy)
+			t.Setenv("GONOPROXY", "")
+
+			data, err := FetchReference(reference)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, proxyTestMod, string(data))
+		})
+	}
+}
+
+func TestFetchReference_FileProxy(t *testing.T) {
+	dir := t.TempDir()
+	// Upper case letters are escaped as ! followed by the lower case letter
+	versionDir := filepath.Join(dir, "github.com", "!org", "platform", "@v")
+	require.NoError(t, os.MkdirAll(versionDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, "v1.42.0.mod"), []byte(proxyTestMod), 0o600))
+
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(dir))
+	t.Setenv("GONOPROXY", "")
+
+	data, err := FetchReference("proxy:github.com/Org/platform@v1.42.0")
+	require.NoError(t, err)
+	assert.Equal(t, proxyTestMod, string(data))
+
+	_, err = FetchReference("proxy:github.com/Org/platform@v1.43.0")
+	assert.Error(t, err)
+}
+
+func TestFetchReference_ProxyNoProxy(t *testing.T) {
+	server := newTestProxy(t, map[string]string{"/github.com/org/platform/@v/v1.42.0.mod": proxyTestMod})
+	t.Setenv("GOPROXY", server.URL)
+
+	direct := []proxyEntry{{url: "direct"}}
+
+	t.Setenv("GONOPROXY", "github.com/org")
+	entries, err := proxyEntries("github.com/org/platform")
+	require.NoError(t, err)
+	assert.Equal(t, direct, entries)
+
+	// GOPRIVATE applies when GONOPROXY is unset
+	require.NoError(t, os.Unsetenv("GONOPROXY"))
+	t.Setenv("GOPRIVATE", "github.com/org/*")
+	entries, err = proxyEntries("github.com/org/platform")
+	require.NoError(t, err)
+	assert.Equal(t, direct, entries)
+
+	t.Setenv("GOPRIVATE", "github.com/other")
+	_, err = FetchReference("proxy:github.com/org/platform@v1.42.0")
+	assert.NoError(t, err)
+}
+
+func TestLoadReference_Proxy(t *testing.T) {
+	server := newTestProxy(t, map[string]string{"/github.com/org/platform/@v/v1.42.0.mod": proxyTestMod})
+	t.Setenv("GOPROXY", server.URL)
+	t.Setenv("GONOPROXY", "")
+
+	referenceMod, err := loadReference("proxy:github.com/org/platform@v1.42.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v0.9.1", BuildVersionMap(referenceMod)["github.com/pkg/errors"])
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/mod/modfile"
+)
+
+// isLocalReplace checks if a replace directive points at a local directory
+func isLocalReplace(rep *modfile.Replace) bool {
+	return rep != nil && modfile.IsDirectoryPath(rep.New.Path)
+}
+
+// CompareReplaces compares the replace directives of the target against the reference
+// and returns the changes needed to make them match. Only modules the target requires
+// are considered: a reference replace is added or updated, and a target replace is
+// removed when the reference requires the module without replacing it. Unless
+// localReplaces is LocalReplacesSync, directives pointing at local directories
+// on either side are left alone.
+func CompareReplaces(targetMod, referenceMod *modfile.File, localReplaces LocalReplacePolicy) []ReplaceChange {
+	var changes []ReplaceChange
+
+	targetVersions := BuildVersionMap(targetMod)
+	refVersions := BuildVersionMap(referenceMod)
+	targetReplaces := BuildReplaceMap(targetMod)
+	refReplaces := BuildReplaceMap(referenceMod)
+
+	skip := func(rep *modfile.Replace) bool {
+		return localReplaces != LocalReplacesSync && isLocalReplace(rep)
+	}
+
+	// Update or remove the replaces the target already has
+	for _, rep := range targetMod.Replace {
+		refRep, exists := refReplaces[rep.Old.String()]
+		if skip(rep) || skip(refRep) {
+			continue
+		}
+
+		change := ReplaceChange{
+			Module:                rep.Old.Path,
+			ModuleVersion:         rep.Old.Version,
+			OldReplacement:        rep.New.Path,
+			OldReplacementVersion: rep.New.Version,
+		}
+		switch {
+		case exists && rep.New != refRep.New:
+			change.NewReplacement = refRep.New.Path
+			change.NewReplacementVersion = refRep.New.Version
+			changes = append(changes, change)
+		case !exists:
+			if _, governed := refVersions[rep.Old.Path]; governed {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	// Add the reference replaces for modules the target requires
+	for _, refRep := range referenceMod.Replace {
+		if _, exists := targetReplaces[refRep.Old.String()]; exists || skip(refRep) {
+			continue
+		}
+		if _, required := targetVersions[refRep.Old.Path]; !required {
+			continue
+		}
+		changes = append(changes, ReplaceChange{
+			Module:                refRep.Old.Path,
+			ModuleVersion:         refRep.Old.Version,
+			NewReplacement:        refRep.New.Path,
+			NewReplacementVersion: refRep.New.Version,
+		})
+	}
+
+	return changes
+}
+
+// ApplyReplaceChanges applies the replace changes to the target modfile
+func ApplyReplaceChanges(targetMod *modfile.File, changes []ReplaceChange) error {
+	for _, change := range changes {
+		if change.NewReplacement == "" {
+			if err := targetMod.DropReplace(change.Module, change.ModuleVersion); err != nil {
+				return fmt.Errorf("failed to remove replace for %s: %w", change.Module, err)
+			}
+			continue
+		}
+		if err := targetMod.AddReplace(change.Module, change.ModuleVersion, change.NewReplacement, change.NewReplacementVersion); err != nil {
+			return fmt.Errorf("failed to update replace for %s: %w", change.Module, err)
+		}
+	}
+
+	// Drop the removed directives from the parsed representation
+	targetMod.Cleanup()
+	return nil
+}
+
+// formatReplacement returns a display form of a replace directive's right-hand side
+func formatReplacement(path, version string) string {
+	switch {
+	case path == "":
+		return "(none)"
+	case version == "":
+		return path
+	default:
+		return path + " " + version
+	}
+}
+
+// formatReplaced returns a display form of a replace directive's left-hand side
+func formatReplaced(module, version string) string {
+	if version == "" {
+		return module
+	}
+	return module + "@" + version
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareReplaces(t *testing.T) {
+	tests := []struct {
+		name             string
+		targetContent    string
+		referenceContent string
+		localReplaces    LocalReplacePolicy
+		expectedChanges  []ReplaceChange
+	}{
+		{
+			name: "add replace from reference",
+			targetContent: `module example.com/test
+
+require github.com/pkg/errors v0.9.1`,
+			referenceContent: `module example.com/reference
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3`,
+			localReplaces: LocalReplacesKeep,
+			expectedChanges: []ReplaceChange{
+				{Module: "github.com/pkg/errors", NewReplacement: "github.com/fork/errors", NewReplacementVersion: "v0.9.3"},
+			},
+		},
+		{
+			name: "update replace",
+			targetContent: `module example.com/test
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.2`,
+			referenceContent: `module example.com/reference
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3`,
+			localReplaces: LocalReplacesKeep,
+			expectedChanges: []ReplaceChange{
+				{
+					Module:                "github.com/pkg/errors",
+					OldReplacement:        "github.com/fork/errors",
+					OldReplacementVersion: "v0.9.2",
+					NewReplacement:        "github.com/fork/errors",
+					NewReplacementVersion: "v0.9.3",
+				},
+			},
+		},
+		{
+			name: "remove replace for module the reference requires unreplaced",
+			targetContent: `module example.com/test
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.2`,
+			referenceContent: `module example.com/reference
+
+require github.com/pkg/errors v0.9.1`,
+			localReplaces: LocalReplacesKeep,
+			expectedChanges: []ReplaceChange{
+				{Module: "github.com/pkg/errors", OldReplacement: "github.com/fork/errors", OldReplacementVersion: "v0.9.2"},
+			},
+		},
+		{
+			name: "keep replace for module unknown to the reference",
+			targetContent: `module example.com/test
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.2`,
+			referenceContent: `module example.com/reference
+
+require golang.org/x/text v0.3.0`,
+			localReplaces:   LocalReplacesKeep,
+			expectedChanges: nil,
+		},
+		{
+			name: "ignore reference replace for module the target does not require",
+			targetContent: `module example.com/test
+
+require github.com/pkg/errors v0.9.1`,
+			referenceContent: `module example.com/reference
+
+replace golang.org/x/text => golang.org/x/text v0.4.0`,
+			localReplaces:   LocalReplacesKeep,
+			expectedChanges: nil,
+		},
+		{
+			name: "version-specific replace",
+			targetContent: `module example.com/test
+
+require github.com/pkg/errors v0.9.1`,
+			referenceContent: `module example.com/reference
+
+replace github.com/pkg/errors v0.9.1 => github.com/fork/errors v0.9.3`,
+			localReplaces: LocalReplacesKeep,
+			expectedChanges: []ReplaceChange{
+				{Module: "github.com/pkg/errors", ModuleVersion: "v0.9.1", NewReplacement: "github.com/fork/errors", NewReplacementVersion: "v0.9.3"},
+			},
+		},
+		{
+			name: "local replaces are kept",
+			targetContent: `module example.com/test
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0
+)
+
+replace github.com/pkg/errors => ../errors`,
+			referenceContent: `module example.com/reference
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3
+
+replace golang.org/x/text => ./text`,
+			localReplaces:   LocalReplacesKeep,
+			expectedChanges: nil,
+		},
+		{
+			name: "local replaces are synced",
+			targetContent: `module example.com/test
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0
+)
+
+replace github.com/pkg/errors => ../errors`,
+			referenceContent: `module example.com/reference
+
+require github.com/pkg/errors v0.9.1
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3
+
+replace golang.org/x/text => ./text`,
+			localReplaces: LocalReplacesSync,
+			expectedChanges: []ReplaceChange{
+				{
+					Module:                "github.com/pkg/errors",
+					OldReplacement:        "../errors",
+					NewReplacement:        "github.com/fork/errors",
+					NewReplacementVersion: "v0.9.3",
+				},
+				{Module: "golang.org/x/text", NewReplacement: "./text"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetMod, err := createTestModFile(tt.targetContent)
+			require.NoError(t, err, "Failed to parse target modfile")
+
+			referenceMod, err := createTestModFile(tt.referenceContent)
+			require.NoError(t, err, "Failed to parse reference modfile")
+
+			changes := CompareReplaces(targetMod, referenceMod, tt.localReplaces)
+			assert.Equal(t, tt.expectedChanges, changes)
+		})
+	}
+}
+
+func TestApplyReplaceChanges(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0
+)
+
+replace golang.org/x/text => golang.org/x/text v0.4.0`)
+	require.NoError(t, err)
+
+	err = ApplyReplaceChanges(targetMod, []ReplaceChange{
+		{Module: "github.com/pkg/errors", NewReplacement: "github.com/fork/errors", NewReplacementVersion: "v0.9.3"},
+		{Module: "golang.org/x/text", OldReplacement: "golang.org/x/text", OldReplacementVersion: "v0.4.0"},
+	})
+	require.NoError(t, err)
+
+	replaces := BuildReplaceMap(targetMod)
+	require.Len(t, replaces, 1)
+	require.Contains(t, replaces, "github.com/pkg/errors")
+	assert.Equal(t, "github.com/fork/errors", replaces["github.com/pkg/errors"].New.Path)
+	assert.Equal(t, "v0.9.3", replaces["github.com/pkg/errors"].New.Version)
+
+	formatted, err := targetMod.Format()
+	require.NoError(t, err)
+	assert.Contains(t, string(formatted), "replace github.com/pkg/errors => github.com/fork/errors v0.9.3")
+	assert.NotContains(t, string(formatted), "golang.org/x/text v0.4.0")
+}
+
+func TestFormatReplacement(t *testing.T) {
+	assert.Equal(t, "(none)", formatReplacement("", ""))
+	assert.Equal(t, "../errors", formatReplacement("../errors", ""))
+	assert.Equal(t, "github.com/fork/errors v0.9.3", formatReplacement("github.com/fork/errors", "v0.9.3"))
+	assert.Equal(t, "github.com/pkg/errors", formatReplaced("github.com/pkg/errors", ""))
+	assert.Equal(t, "github.com/pkg/errors@v0.9.1", formatReplaced("github.com/pkg/errors", "v0.9.1"))
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/mod/modfile"
+)
+
+// reportSchemaVersion is the version of the machine-readable report layout.
+// It is bumped whenever a field is renamed, removed or changes meaning.
+const reportSchemaVersion = 1
+
+// SyncTargetReport contains the sync outcome for a single target go.mod file
+type SyncTargetReport struct {
+	Target  string      `json:"target"`
+	Error   string      `json:"error,omitempty"`
+	Changes int         `json:"changes"`
+	Result  *SyncResult `json:"result,omitempty"`
+
+	targetMod *modfile.File // updated target, used for previews
+	diff      string        // unified diff of the target file
+}
+
+// SyncReport contains the combined outcome of a sync run over one or more targets
+type SyncReport struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Command       string             `json:"command"`
+	Reference     string             `json:"reference"`
+	DryRun        bool               `json:"dryRun"`
+	TotalChanges  int                `json:"totalChanges"`
+	ChangedFiles  int                `json:"changedFiles"`
+	FailedFiles   int                `json:"failedFiles"`
+	Targets       []SyncTargetReport `json:"targets"`
+}
+
+// CheckTargetReport contains the check outcome for a single target go.mod file
+type CheckTargetReport struct {
+	Target     string       `json:"target"`
+	Error      string       `json:"error,omitempty"`
+	Mismatches int          `json:"mismatches"`
+	Result     *CheckResult `json:"result,omitempty"`
+
+	targetMod    *modfile.File // parsed target, used for line positions
+	referenceMod *modfile.File // reference the target was checked against
+}
+
+// CheckReport contains the combined outcome of a check run over one or more targets
+type CheckReport struct {
+	SchemaVersion   int                 `json:"schemaVersion"`
+	Command         string              `json:"command"`
+	Reference       string              `json:"reference"`
+	Passed          bool                `json:"passed"`
+	TotalMismatches int                 `json:"totalMismatches"`
+	FailedFiles     int                 `json:"failedFiles"`
+	Targets         []CheckTargetReport `json:"targets"`
+
+	constraints *ConstraintSet // policy file constraints the targets were checked against
+}
+
+// RunSync syncs every target against the reference and collects the outcomes.
+// A target that fails does not stop the run; its error is recorded instead.
+func RunSync(targets []string, referenceName string, referenceMod *modfile.File, opts SyncOptions, dryRun bool) *SyncReport {
+	report := &SyncReport{
+		SchemaVersion: reportSchemaVersion,
+		Command:       "sync",
+		Reference:     referenceName,
+		DryRun:        dryRun,
+		Targets:       make([]SyncTargetReport, 0, len(targets)),
+	}
+
+	for _, path := range targets {
+		targetReport := SyncTargetReport{Target: path}
+
+		result, targetMod, diff, err := syncTarget(path, referenceMod, opts, dryRun)
+		if err != nil {
+			targetReport.Error = err.Error()
+			report.FailedFiles++
+		} else {
+			sortSyncResult(result)
+			targetReport.Result = result
+			targetReport.Changes = result.TotalChanges()
+			targetReport.targetMod = targetMod
+			targetReport.diff = diff
+			report.TotalChanges += targetReport.Changes
+			if targetReport.Changes > 0 {
+				report.ChangedFiles++
+			}
+		}
+
+		report.Targets = append(report.Targets, targetReport)
+	}
+
+	return report
+}
+
+// RunCheck checks every target against the reference and collects the outcomes.
+// A target that cannot be read counts as failed; its error is recorded.
+func RunCheck(targets []string, referenceName string, referenceMod *modfile.File, opts CheckOptions) *CheckReport {
+	report := &CheckReport{
+		SchemaVersion: reportSchemaVersion,
+		Command:       "check",
+		Reference:     referenceName,
+		Targets:       make([]CheckTargetReport, 0, len(targets)),
+		constraints:   opts.Constraints,
+	}
+
+	for _, path := range targets {
+		targetReport := CheckTargetReport{Target: path}
+
+		targetMod, targetReference, err := checkTargetReference(path, referenceMod, opts)
+		if err != nil {
+			targetReport.Error = err.Error()
+			report.FailedFiles++
+		} else {
+			result := CheckVersionsWithOptions(targetMod, targetReference, opts)
+			sortCheckResult(result)
+			targetReport.Result = result
+			targetReport.Mismatches = result.TotalMismatches()
+			targetReport.targetMod = targetMod
+			targetReport.referenceMod = targetReference
+			report.TotalMismatches += targetReport.Mismatches
+			if targetReport.Mismatches > 0 {
+				report.FailedFiles++
+			}
+		}
+
+		report.Targets = append(report.Targets, targetReport)
+	}
+
+	report.Passed = report.FailedFiles == 0
+	return report
+}
+
+// checkTargetReference reads a target and returns it with the reference it is
+// checked against, which is resolved per target for a latest reference
+func checkTargetReference(path string, referenceMod *modfile.File, opts CheckOptions) (*modfile.File, *modfile.File, error) {
+	targetMod, err := readTarget(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Latest != nil {
+		if referenceMod, err = opts.Latest.Reference(targetMod); err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve latest versions: %w", err)
+		}
+	}
+	if err := opts.Constraints.loadCandidates(targetMod); err != nil {
+		return nil, nil, fmt.Errorf("failed to load policy file candidates: %w", err)
+	}
+	return targetMod, referenceMod, nil
+}
+
+// sortSyncResult sorts every list of a sync result and replaces nil lists
+// with empty ones, so reports have a fixed order and shape
+func sortSyncResult(result *SyncResult) {
+	result.DependencyChanges = sortedByKey(result.DependencyChanges, func(c VersionChange) string { return c.Module })
+	result.AddedModules = sortedByKey(result.AddedModules, func(c ModuleChange) string { return c.Module })
+	result.RemovedModules = sortedByKey(result.RemovedModules, func(c ModuleChange) string { return c.Module })
+	result.GodebugChanges = sortedByKey(result.GodebugChanges, func(c GodebugChange) string { return c.Key })
+	result.AddedTools = sortedByKey(result.AddedTools, func(tool string) string { return tool })
+	result.ReplaceChanges = sortedByKey(result.ReplaceChanges, func(c ReplaceChange) string {
+		return formatReplaced(c.Module, c.ModuleVersion)
+	})
+	result.ExcludeChanges = sortedByKey(result.ExcludeChanges, func(c ExcludeChange) string {
+		return c.Module + "@" + c.Version
+	})
+}
+
+// sortCheckResult sorts every list of a check result and replaces nil lists
+// with empty ones, so reports have a fixed order and shape
+func sortCheckResult(result *CheckResult) {
+	result.DependencyMismatches = sortedByKey(result.DependencyMismatches, func(m VersionMismatch) string { return m.Module })
+	result.GodebugMismatches = sortedByKey(result.GodebugMismatches, func(m GodebugMismatch) string { return m.Key })
+	result.MissingTools = sortedByKey(result.MissingTools, func(tool string) string { return tool })
+	result.ReplaceMismatches = sortedByKey(result.ReplaceMismatches, func(m ReplaceMismatch) string {
+		return formatReplaced(m.Module, m.ModuleVersion)
+	})
+	result.ExcludedRequirements = sortedByKey(result.ExcludedRequirements, func(e ExcludedRequirement) string {
+		return e.Module + "@" + e.Version
+	})
+}
+
+// sortedByKey returns the items stably sorted by key, never returning nil
+func sortedByKey[T any](items []T, key func(T) string) []T {
+	if items == nil {
+		return []T{}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return key(items[i]) < key(items[j])
+	})
+	return items
+}
+
+// writeJSON writes a report as indented JSON
+func writeJSON(w io.Writer, report any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportTargetContent = `module example.com/test
+
+go 1.21
+
+require (
+	golang.org/x/text v0.3.0
+	github.com/pkg/errors v0.9.1
+	github.com/extra/dep v1.0.0
+	cloud.google.com/go v0.100.0
+)
+`
+
+const reportReferenceContent = `module example.com/reference
+
+go 1.22
+
+require (
+	github.com/pkg/errors v0.9.2
+	golang.org/x/text v0.4.0
+	cloud.google.com/go v0.110.0
+)
+`
+
+func TestRunCheck(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "svc/go.mod", reportTargetContent)
+	missing := filepath.Join(dir, "missing", "go.mod")
+
+	referenceMod, err := createTestModFile(reportReferenceContent)
+	require.NoError(t, err)
+
+	report := RunCheck([]string{target, missing}, "ref/go.mod", referenceMod, CheckOptions{Strict: true, Directives: DefaultDirectives()})
+
+	assert.Equal(t, reportSchemaVersion, report.SchemaVersion)
+	assert.Equal(t, "check", report.Command)
+	assert.Equal(t, "ref/go.mod", report.Reference)
+	assert.False(t, report.Passed)
+	assert.Equal(t, 5, report.TotalMismatches)
+	assert.Equal(t, 2, report.FailedFiles)
+	require.Len(t, report.Targets, 2)
+
+	var modules []string
+	for _, m := range report.Targets[0].Result.DependencyMismatches {
+		modules = append(modules, m.Module)
+	}
+	assert.Equal(t, []string{"cloud.google.com/go", "github.com/extra/dep", "github.com/pkg/errors", "golang.org/x/text"}, modules, "mismatches must be sorted by module")
+
+	assert.NotEmpty(t, report.Targets[1].Error)
+	assert.Nil(t, report.Targets[1].Result)
+}
+
+func TestRunCheck_Passed(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", reportReferenceContent)
+
+	referenceMod, err := createTestModFile(reportReferenceContent)
+	require.NoError(t, err)
+
+	report := RunCheck([]string{target}, "ref/go.mod", referenceMod, CheckOptions{Directives: DefaultDirectives()})
+	assert.True(t, report.Passed)
+	assert.Equal(t, 0, report.TotalMismatches)
+}
+
+func TestRunSync(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", reportTargetContent)
+
+	referenceMod, err := createTestModFile(reportReferenceContent)
+	require.NoError(t, err)
+
+	t.Run("dry run", func(t *testing.T) {
+		report := RunSync([]string{target}, "ref/go.mod", referenceMod, SyncOptions{Directives: DefaultDirectives()}, true)
+		assert.Equal(t, "sync", report.Command)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 4, report.TotalChanges)
+		assert.Equal(t, 1, report.ChangedFiles)
+		assert.Equal(t, 0, report.FailedFiles)
+
+		var modules []string
+		for _, c := range report.Targets[0].Result.DependencyChanges {
+			modules = append(modules, c.Module)
+		}
+		assert.Equal(t, []string{"cloud.google.com/go", "github.com/pkg/errors", "golang.org/x/text"}, modules)
+
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		assert.Equal(t, reportTargetContent, string(data), "dry run must not modify the target")
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		report := RunSync([]string{target}, "ref/go.mod", referenceMod, SyncOptions{Directives: DefaultDirectives()}, false)
+		assert.Equal(t, 4, report.TotalChanges)
+
+		data, err := os.ReadFile(target)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "github.com/pkg/errors v0.9.2")
+	})
+}
+
+func TestWriteJSON_Check(t *testing.T) {
+	dir := t.TempDir()
+	target := writeTestFile(t, dir, "go.mod", reportTargetContent)
+
+	referenceMod, err := createTestModFile(reportReferenceContent)
+	require.NoError(t, err)
+
+	report := RunCheck([]string{target}, "https://example.com/go.mod", referenceMod, CheckOptions{Directives: DefaultDirectives()})
+
+	var buf bytes.Buffer
+	require.NoError(t, writeJSON(&buf, report))
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+
+	assert.EqualValues(t, reportSchemaVersion, decoded["schemaVersion"])
+	assert.Equal(t, "https://example.com/go.mod", decoded["reference"])
+	assert.Equal(t, false, decoded["passed"])
+
+	targets := decoded["targets"].([]any)
+	require.Len(t, targets, 1)
+	result := targets[0].(map[string]any)["result"].(map[string]any)
+
+	goMismatch := result["goVersionMismatch"].(map[string]any)
+	assert.Equal(t, "1.21", goMismatch["targetVersion"])
+	assert.Equal(t, "1.22", goMismatch["referenceVersion"])
+
+	mismatches := result["dependencyMismatches"].([]any)
+	require.Len(t, mismatches, 3)
+	first := mismatches[0].(map[string]any)
+	assert.Equal(t, "cloud.google.com/go", first["module"])
+	assert.Equal(t, "v0.100.0", first["targetVersion"])
+	assert.Equal(t, "v0.110.0", first["referenceVersion"])
+	assert.Equal(t, false, first["onlyInTarget"])
+
+	assert.Equal(t, []any{}, result["replaceMismatches"], "empty lists are encoded as []")
+}
+
+func TestSortedByKey(t *testing.T) {
+	assert.Equal(t, []string{}, sortedByKey([]string(nil), func(s string) string { return s }))
+	assert.Equal(t, []string{"a", "b", "c"}, sortedByKey([]string{"c", "a", "b"}, func(s string) string { return s }))
+}
@@ -0,0 +1,103 @@
+package main
+
+import "io"
+
+// sarifSchema is the JSON schema of the SARIF 2.1.0 log format
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifLog is the top-level object of a SARIF 2.1.0 log
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// buildSARIF converts annotations into a SARIF 2.1.0 log with a single run
+func buildSARIF(annotations []Annotation) *sarifLog {
+	rules := make([]sarifRule, 0, len(annotationRules))
+	for _, rule := range annotationRules {
+		rules = append(rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}})
+	}
+
+	results := make([]sarifResult, 0, len(annotations))
+	for _, a := range annotations {
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: a.File}}}
+		if a.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: a.Line}
+		}
+		results = append(results, sarifResult{
+			RuleID:    a.RuleID,
+			Level:     "error",
+			Message:   sarifMessage{Text: a.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	return &sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "gomodsync",
+				Version:        version,
+				InformationURI: "https://github.com/dsolerh/gomodsync",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// writeSARIF writes the annotations as an indented SARIF 2.1.0 log
+func writeSARIF(w io.Writer, annotations []Annotation) error {
+	return writeJSON(w, buildSARIF(annotations))
+}
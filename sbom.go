@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// Supported SBOM formats
+const (
+	SBOMCycloneDX = "CycloneDX"
+	SBOMSPDX      = "SPDX"
+)
+
+// golangPurlPrefix starts the package URL of every Go module
+const golangPurlPrefix = "pkg:golang/"
+
+// sbomHeader holds the fields that identify an SBOM document
+type sbomHeader struct {
+	BomFormat   string `json:"bomFormat"`
+	SpdxVersion string `json:"spdxVersion"`
+}
+
+// cycloneDXComponent is a CycloneDX component; components may nest
+type cycloneDXComponent struct {
+	Purl       string               `json:"purl"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// cycloneDXDocument is the part of a CycloneDX JSON BOM that holds purls
+type cycloneDXDocument struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// spdxDocument is the part of an SPDX JSON document that holds purls
+type spdxDocument struct {
+	Packages []struct {
+		ExternalRefs []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+// DetectSBOMFormat returns the format of a JSON SBOM, or "" if data is not one
+func DetectSBOMFormat(data []byte) string {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ""
+	}
+	var header sbomHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return ""
+	}
+	switch {
+	case header.BomFormat == SBOMCycloneDX:
+		return SBOMCycloneDX
+	case strings.HasPrefix(header.SpdxVersion, "SPDX-"):
+		return SBOMSPDX
+	default:
+		return ""
+	}
+}
+
+// ParseSBOM extracts the Go modules of a CycloneDX or SPDX JSON SBOM from
+// their pkg:golang purls. A module listed at several versions maps to the
+// highest one, and the stdlib entry some generators add is skipped.
+func ParseSBOM(data []byte) (VersionMap, error) {
+	var purls []string
+	switch DetectSBOMFormat(data) {
+	case SBOMCycloneDX:
+		var doc cycloneDXDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid CycloneDX SBOM: %w", err)
+		}
+		purls = cycloneDXPurls(doc.Components)
+	case SBOMSPDX:
+		var doc spdxDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid SPDX SBOM: %w", err)
+		}
+		for _, pkg := range doc.Packages {
+			for _, ref := range pkg.ExternalRefs {
+				if ref.ReferenceType == "purl" {
+					purls = append(purls, ref.ReferenceLocator)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("not a CycloneDX or SPDX JSON SBOM")
+	}
+
+	versions := make(VersionMap)
+	for _, purl := range purls {
+		module, version, ok := parseGolangPurl(purl)
+		if !ok || module == "stdlib" {
+			continue
+		}
+		if current, exists := versions[module]; !exists || semver.Compare(version, current) > 0 {
+			versions[module] = version
+		}
+	}
+	return versions, nil
+}
+
+// cycloneDXPurls returns the purls of the components and their nested components
+func cycloneDXPurls(components []cycloneDXComponent) []string {
+	var purls []string
+	for _, component := range components {
+		if component.Purl != "" {
+			purls = append(purls, component.Purl)
+		}
+		purls = append(purls, cycloneDXPurls(component.Components)...)
+	}
+	return purls
+}
+
+// parseGolangPurl returns the module path and version of a pkg:golang purl
+// such as pkg:golang/github.com/pkg/errors@v0.9.1. Qualifiers and subpaths are
+// dropped, and a version missing its v prefix gets one.
+func parseGolangPurl(purl string) (module, version string, ok bool) {
+	rest, found := strings.CutPrefix(purl, golangPurlPrefix)
+	if !found {
+		return "", "", false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+
+	i := strings.LastIndex(rest, "@")
+	if i <= 0 {
+		return "", "", false
+	}
+	module, err := url.PathUnescape(rest[:i])
+	if err != nil {
+		return "", "", false
+	}
+	version, err = url.PathUnescape(rest[i+1:])
+	if err != nil {
+		return "", "", false
+	}
+
+	if module == "stdlib" {
+		return module, version, true
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return "", "", false
+	}
+	return module, version, true
+}
+
+// BuildSBOMReference converts the modules of an SBOM into a reference modfile
+func BuildSBOMReference(filename string, data []byte) (*modfile.File, error) {
+	versions, err := ParseSBOM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reference, err := ParseGoMod(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	addRequires(reference, versions, nil)
+	return reference, nil
+}
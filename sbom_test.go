@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cycloneDXTestSBOM = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "metadata": {
+    "component": {"type": "application", "name": "api", "purl": "pkg:golang/example.com/api@v1.0.0"}
+  },
+  "components": [
+    {"type": "library", "name": "github.com/pkg/errors", "version": "v0.9.1", "purl": "pkg:golang/github.com/pkg/errors@v0.9.1"},
+    {
+      "type": "library",
+      "name": "golang.org/x/text",
+      "purl": "pkg:golang/golang.org/x/text@v0.14.0?type=module",
+      "components": [
+        {"type": "library", "name": "golang.org/x/text/language", "purl": "pkg:golang/golang.org/x/text@v0.14.0#language"}
+      ]
+    },
+    {"type": "library", "name": "github.com/docker/docker", "purl": "pkg:golang/github.com/docker/docker@v24.0.7%2Bincompatible"},
+    {"type": "library", "name": "stdlib", "purl": "pkg:golang/stdlib@1.22.3"},
+    {"type": "library", "name": "left-pad", "purl": "pkg:npm/left-pad@1.3.0"},
+    {"type": "library", "name": "no purl"}
+  ]
+}`
+
+const spdxTestSBOM = `{
+  "spdxVersion": "SPDX-2.3",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "packages": [
+    {
+      "name": "github.com/pkg/errors",
+      "versionInfo": "v0.9.1",
+      "externalRefs": [
+        {"referenceCategory": "SECURITY", "referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:pkg:errors:v0.9.1:*:*:*:*:*:*:*"},
+        {"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:golang/github.com/pkg/errors@v0.9.1"}
+      ]
+    },
+    {
+      "name": "golang.org/x/text",
+      "externalRefs": [
+        {"referenceCategory": "PACKAGE_MANAGER", "referenceType": "purl", "referenceLocator": "pkg:golang/golang.org/x/text@0.13.0"}
+      ]
+    },
+    {
+      "name": "golang.org/x/text",
+      "externalRefs": [
+        {"referenceCategory": "PACKAGE_MANAGER", "referenceType": "purl", "referenceLocator": "pkg:golang/golang.org/x/text@v0.14.0"}
+      ]
+    },
+    {"name": "no refs"}
+  ]
+}`
+
+func TestDetectSBOMFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"CycloneDX", cycloneDXTestSBOM, SBOMCycloneDX},
+		{"SPDX", spdxTestSBOM, SBOMSPDX},
+		{"go.mod", "module example.com/test\n\ngo 1.21\n", ""},
+		{"other JSON", `{"Path": "example.com/test"}`, ""},
+		{"invalid JSON", `{"bomFormat": `, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectSBOMFormat([]byte(tt.input)))
+		})
+	}
+}
+
+func TestParseGolangPurl(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectModule  string
+		expectVersion string
+		ok            bool
+	}{
+		{"pkg:golang/github.com/pkg/errors@v0.9.1", "github.com/pkg/errors", "v0.9.1", true},
+		{"pkg:golang/github.com/Masterminds/semver/v3@v3.2.1", "github.com/Masterminds/semver/v3", "v3.2.1", true},
+		{"pkg:golang/golang.org/x/text@v0.14.0?type=module#language", "golang.org/x/text", "v0.14.0", true},
+		{"pkg:golang/github.com/docker/docker@v24.0.7%2Bincompatible", "github.com/docker/docker", "v24.0.7+incompatible", true},
+		{"pkg:golang/golang.org/x/text@0.14.0", "golang.org/x/text", "v0.14.0", true},
+		{"pkg:golang/golang.org/x/text", "", "", false},
+		{"pkg:golang/golang.org/x/text@latest", "", "", false},
+		{"pkg:npm/left-pad@1.3.0", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			module, version, ok := parseGolangPurl(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expectModule, module)
+			assert.Equal(t, tt.expectVersion, version)
+		})
+	}
+}
+
+func TestParseSBOM(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected VersionMap
+	}{
+		{
+			name:  "CycloneDX",
+			input: cycloneDXTestSBOM,
+			expected: VersionMap{
+				"github.com/pkg/errors":    "v0.9.1",
+				"golang.org/x/text":        "v0.14.0",
+				"github.com/docker/docker": "v24.0.7+incompatible",
+			},
+		},
+		{
+			name:  "SPDX keeps the highest version",
+			input: spdxTestSBOM,
+			expected: VersionMap{
+				"github.com/pkg/errors": "v0.9.1",
+				"golang.org/x/text":     "v0.14.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := ParseSBOM([]byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, versions)
+		})
+	}
+
+	_, err := ParseSBOM([]byte("module example.com/test\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadReference_SBOM(t *testing.T) {
+	dir := t.TempDir()
+	sbomPath := writeTestFile(t, dir, "sbom.cdx.json", cycloneDXTestSBOM)
+
+	referenceMod, err := loadReference(sbomPath)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.9.1", BuildVersionMap(referenceMod)["github.com/pkg/errors"])
+	assert.Nil(t, referenceMod.Go)
+
+	targetMod, err := createTestModFile("module example.com/api\n\ngo 1.22\n\nrequire (\n\tgithub.com/pkg/errors v0.9.0\n\tgolang.org/x/text v0.14.0\n)\n")
+	require.NoError(t, err)
+	result := CheckVersions(targetMod, referenceMod, false)
+	require.Len(t, result.DependencyMismatches, 1)
+	assert.Equal(t, "github.com/pkg/errors", result.DependencyMismatches[0].Module)
+	assert.Nil(t, result.GoVersionMismatch)
+}
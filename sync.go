@@ -9,11 +9,38 @@ import (
 // CompareVersions compares target versions against reference versions
 // and returns a list of changes that need to be made
 func CompareVersions(targetMod *modfile.File, refVersions VersionMap) []VersionChange {
+	return CompareVersionsWithPolicy(targetMod, refVersions, PolicyExact)
+}
+
+// CompareVersionsWithPolicy compares target versions against reference versions
+// and returns the changes that the version policy requires
+func CompareVersionsWithPolicy(targetMod *modfile.File, refVersions VersionMap, policy VersionPolicy) []VersionChange {
+	return CompareVersionsWithConstraints(targetMod, refVersions, policy, nil, nil)
+}
+
+// CompareVersionsWithConstraints is like CompareVersionsWithPolicy, but modules
+// with a policy file constraint are moved to the lowest or highest candidate
+// version that satisfies it instead, as the constraint set prefers. Candidates
+// are the versions of every reference layer in sources, or of refVersions
+// without layers, plus any proxy releases the constraint set loaded.
+func CompareVersionsWithConstraints(targetMod *modfile.File, refVersions VersionMap, policy VersionPolicy, constraints *ConstraintSet, sources *ReferenceSources) []VersionChange {
 	var changes []VersionChange
 
 	for _, req := range targetMod.Require {
+		if constraint, ok := constraints.Lookup(req.Mod.Path); ok {
+			newVersion := constraint.Resolve(req.Mod.Version, constraints.candidates(req.Mod.Path, refVersions, sources), constraints.Prefer)
+			if newVersion != "" {
+				changes = append(changes, VersionChange{
+					Module:     req.Mod.Path,
+					OldVersion: req.Mod.Version,
+					NewVersion: newVersion,
+				})
+			}
+			continue
+		}
+
 		if refVersion, exists := refVersions[req.Mod.Path]; exists {
-			if req.Mod.Version != refVersion {
+			if policy.ShouldUpdate(req.Mod.Version, refVersion) {
 				changes = append(changes, VersionChange{
 					Module:     req.Mod.Path,
 					OldVersion: req.Mod.Version,
@@ -36,23 +63,196 @@ func ApplyVersionChanges(targetMod *modfile.File, changes []VersionChange) error
 	return nil
 }
 
+// FindMissingModules returns the reference requirements that the target
+// does not require, in reference order
+func FindMissingModules(targetMod, referenceMod *modfile.File, indirect bool) []ModuleChange {
+	var missing []ModuleChange
+
+	targetVersions := BuildVersionMap(targetMod)
+	for _, req := range referenceMod.Require {
+		if _, exists := targetVersions[req.Mod.Path]; exists {
+			continue
+		}
+		targetVersions[req.Mod.Path] = req.Mod.Version
+		missing = append(missing, ModuleChange{
+			Module:   req.Mod.Path,
+			Version:  req.Mod.Version,
+			Indirect: indirect,
+		})
+	}
+
+	return missing
+}
+
+// FindPrunableModules returns the target requirements that are
+// absent from the reference, in target order
+func FindPrunableModules(targetMod *modfile.File, refVersions VersionMap) []ModuleChange {
+	var prunable []ModuleChange
+
+	for _, req := range targetMod.Require {
+		if _, exists := refVersions[req.Mod.Path]; !exists {
+			prunable = append(prunable, ModuleChange{
+				Module:   req.Mod.Path,
+				Version:  req.Mod.Version,
+				Indirect: req.Indirect,
+			})
+		}
+	}
+
+	return prunable
+}
+
+// ApplyModuleChanges adds the added requirements to and drops the
+// removed requirements from the target modfile
+func ApplyModuleChanges(targetMod *modfile.File, added, removed []ModuleChange) error {
+	for _, change := range added {
+		targetMod.AddNewRequire(change.Module, change.Version, change.Indirect)
+	}
+
+	for _, change := range removed {
+		if err := targetMod.DropRequire(change.Module); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", change.Module, err)
+		}
+	}
+
+	// Drop the removed requirements from the parsed representation
+	targetMod.Cleanup()
+	return nil
+}
+
 // SyncVersions is the main business logic function that syncs versions
-// from reference to target, including the Go version
+// from reference to target, including the Go version and exclude directives
 func SyncVersions(targetMod, referenceMod *modfile.File) (*SyncResult, error) {
+	return SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Directives: DefaultDirectives(), Excludes: true})
+}
+
+// SyncVersionsWithOptions syncs dependency versions from reference to target
+// like SyncVersions, together with the directives selected in opts
+func SyncVersionsWithOptions(targetMod, referenceMod *modfile.File, opts SyncOptions) (*SyncResult, error) {
 	result := &SyncResult{}
 
+	if err := syncRequirements(targetMod, referenceMod, opts, result); err != nil {
+		return nil, err
+	}
+	if err := syncDirectives(targetMod, referenceMod, opts, result); err != nil {
+		return nil, err
+	}
+
+	// Sync replace directives
+	if opts.Replaces {
+		replaceChanges := CompareReplaces(targetMod, referenceMod, opts.LocalReplaces)
+		if err := ApplyReplaceChanges(targetMod, replaceChanges); err != nil {
+			return nil, err
+		}
+		result.ReplaceChanges = replaceChanges
+	}
+
+	if opts.Excludes {
+		if err := syncExcludes(targetMod, referenceMod, result); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Sources.annotateSyncResult(result, referenceMod)
+	return result, nil
+}
+
+// syncRequirements syncs the versions of the target's requirements, then adds
+// missing and prunes extra requirements as opts ask
+func syncRequirements(targetMod, referenceMod *modfile.File, opts SyncOptions, result *SyncResult) error {
+	// Denied requirements are only dropped when pruning
+	denied := opts.Constraints.deniedRequirements(targetMod)
+	if len(denied) > 0 && !opts.Prune {
+		return deniedError(denied)
+	}
+
 	// Sync dependency versions
 	refVersions := BuildVersionMap(referenceMod)
-	depChanges := CompareVersions(targetMod, refVersions)
+	depChanges := CompareVersionsWithConstraints(targetMod, refVersions, opts.Policy, opts.Constraints, opts.Sources)
 
 	if len(depChanges) > 0 {
 		if err := ApplyVersionChanges(targetMod, depChanges); err != nil {
-			return nil, err
+			return err
 		}
 	}
 	result.DependencyChanges = depChanges
 
+	// Add missing and prune extra requirements
+	if opts.AddMissing {
+		missing := FindMissingModules(targetMod, referenceMod, opts.AddMissingIndirect)
+		result.AddedModules = opts.Constraints.filterModuleChanges(missing, ConstraintDeny, ConstraintIgnore)
+	}
+	if opts.Prune {
+		prunable := FindPrunableModules(targetMod, refVersions)
+		result.RemovedModules = append(opts.Constraints.filterModuleChanges(prunable, ConstraintIgnore, ConstraintDeny), denied...)
+	}
+	return ApplyModuleChanges(targetMod, result.AddedModules, result.RemovedModules)
+}
+
+// syncDirectives syncs the go, toolchain, godebug and tool directives
+// selected in opts
+func syncDirectives(targetMod, referenceMod *modfile.File, opts SyncOptions, result *SyncResult) error {
 	// Sync Go version
+	if opts.Directives[DirectiveGo] {
+		goChange, err := syncGoVersion(targetMod, referenceMod, opts.GoPolicy, opts.GoMax)
+		if err != nil {
+			return err
+		}
+		result.GoVersionChange = goChange
+	}
+
+	// Sync toolchain
+	if opts.Directives[DirectiveToolchain] {
+		if change := CompareToolchain(targetMod, referenceMod); change != nil {
+			if err := targetMod.AddToolchainStmt(change.NewVersion); err != nil {
+				return fmt.Errorf("failed to update toolchain: %w", err)
+			}
+			result.ToolchainChange = change
+		}
+	}
+
+	// Sync godebug settings
+	if opts.Directives[DirectiveGodebug] {
+		godebugChanges := CompareGodebug(targetMod, referenceMod)
+		if err := ApplyGodebugChanges(targetMod, godebugChanges); err != nil {
+			return err
+		}
+		result.GodebugChanges = godebugChanges
+	}
+
+	// Sync tool directives
+	if opts.Directives[DirectiveTool] {
+		addedTools := CompareTools(targetMod, referenceMod)
+		if err := ApplyToolChanges(targetMod, addedTools); err != nil {
+			return err
+		}
+		result.AddedTools = addedTools
+	}
+	return nil
+}
+
+// syncExcludes copies the reference's exclude directives into the target,
+// moving requirements off excluded versions first
+func syncExcludes(targetMod, referenceMod *modfile.File, result *SyncResult) error {
+	moved, err := MoveExcludedRequirements(targetMod, referenceMod)
+	if err != nil {
+		return err
+	}
+	result.DependencyChanges = append(result.DependencyChanges, moved...)
+
+	excludeChanges := CompareExcludes(targetMod, referenceMod)
+	if err := ApplyExcludeChanges(targetMod, excludeChanges); err != nil {
+		return err
+	}
+	result.ExcludeChanges = excludeChanges
+	return nil
+}
+
+// syncGoVersion updates the go directive of the target to the reference version
+// when the policy requires it and returns the change made, or nil if none was
+// needed. If goMax is set, the directive is never raised above it: a reference
+// version beyond the cap is lowered to the cap.
+func syncGoVersion(targetMod, referenceMod *modfile.File, policy GoVersionPolicy, goMax string) (*GoVersionChange, error) {
 	var targetGoVersion, refGoVersion string
 	if targetMod.Go != nil {
 		targetGoVersion = targetMod.Go.Version
@@ -61,15 +261,30 @@ func SyncVersions(targetMod, referenceMod *modfile.File) (*SyncResult, error) {
 		refGoVersion = referenceMod.Go.Version
 	}
 
-	if refGoVersion != "" && targetGoVersion != refGoVersion {
-		result.GoVersionChange = &GoVersionChange{
-			OldVersion: targetGoVersion,
-			NewVersion: refGoVersion,
-		}
-		if err := targetMod.AddGoStmt(refGoVersion); err != nil {
-			return nil, fmt.Errorf("failed to update Go version: %w", err)
+	if refGoVersion == "" {
+		return nil, nil
+	}
+
+	newGoVersion := refGoVersion
+	if goMax != "" {
+		if cmp, ok := CompareGoVersions(newGoVersion, goMax); ok && cmp > 0 {
+			newGoVersion = goMax
+			// Never raise past the cap, and never lower a target because of it
+			if cmp, ok := CompareGoVersions(targetGoVersion, goMax); ok && cmp >= 0 {
+				return nil, nil
+			}
 		}
 	}
 
-	return result, nil
+	if !policy.ShouldUpdate(targetGoVersion, newGoVersion) {
+		return nil, nil
+	}
+
+	if err := targetMod.AddGoStmt(newGoVersion); err != nil {
+		return nil, fmt.Errorf("failed to update Go version: %w", err)
+	}
+	return &GoVersionChange{
+		OldVersion: targetGoVersion,
+		NewVersion: newGoVersion,
+	}, nil
 }
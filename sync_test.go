@@ -411,3 +411,273 @@ require (
 		})
 	}
 }
+
+func TestSyncVersionsWithOptions_Replaces(t *testing.T) {
+	targetContent := `module example.com/test
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0
+)
+
+replace golang.org/x/text => golang.org/x/text v0.3.5
+`
+	referenceContent := `module example.com/reference
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0
+)
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3
+`
+
+	t.Run("replaces disabled", func(t *testing.T) {
+		targetMod, err := createTestModFile(targetContent)
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(referenceContent)
+		require.NoError(t, err)
+
+		result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, result.ReplaceChanges)
+		assert.Equal(t, 0, result.TotalChanges())
+	})
+
+	t.Run("replaces enabled", func(t *testing.T) {
+		targetMod, err := createTestModFile(targetContent)
+		require.NoError(t, err)
+		referenceMod, err := createTestModFile(referenceContent)
+		require.NoError(t, err)
+
+		result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Replaces: true, LocalReplaces: LocalReplacesKeep})
+		require.NoError(t, err)
+		assert.Len(t, result.ReplaceChanges, 2)
+		assert.Equal(t, 2, result.TotalChanges())
+
+		replaces := BuildReplaceMap(targetMod)
+		assert.Len(t, replaces, 1)
+		assert.Contains(t, replaces, "github.com/pkg/errors")
+	})
+}
+
+func TestSyncVersionsWithOptions_Excludes(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+go 1.21
+
+require golang.org/x/crypto v0.4.0
+`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+go 1.21
+
+exclude golang.org/x/crypto v0.2.0
+`)
+	require.NoError(t, err)
+
+	result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Excludes: true})
+	require.NoError(t, err)
+	assert.Equal(t, []ExcludeChange{{Module: "golang.org/x/crypto", Version: "v0.2.0"}}, result.ExcludeChanges)
+	assert.Equal(t, 1, result.TotalChanges())
+	require.Len(t, targetMod.Exclude, 1)
+	assert.Equal(t, "v0.2.0", targetMod.Exclude[0].Mod.Version)
+}
+
+func TestSyncVersionsWithOptions_Directives(t *testing.T) {
+	targetContent := `module example.com/test
+
+go 1.23
+
+toolchain go1.23.1
+
+godebug panicnil=1
+`
+	referenceContent := `module example.com/reference
+
+go 1.24
+
+toolchain go1.24.2
+
+godebug panicnil=0
+
+tool golang.org/x/tools/cmd/stringer
+`
+
+	tests := []struct {
+		name                  string
+		directives            DirectiveSet
+		expectGoVersionChange bool
+		expectToolchainChange bool
+		expectedGodebug       int
+		expectedTools         int
+	}{
+		{"go only", DirectiveSet{DirectiveGo: true}, true, false, 0, 0},
+		{"toolchain only", DirectiveSet{DirectiveToolchain: true}, false, true, 0, 0},
+		{"godebug and tool", DirectiveSet{DirectiveGodebug: true, DirectiveTool: true}, false, false, 1, 1},
+		{"none", DirectiveSet{}, false, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetMod, err := createTestModFile(targetContent)
+			require.NoError(t, err)
+			referenceMod, err := createTestModFile(referenceContent)
+			require.NoError(t, err)
+
+			result, err := SyncVersionsWithOptions(targetMod, referenceMod, SyncOptions{Directives: tt.directives})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectGoVersionChange, result.GoVersionChange != nil)
+			assert.Equal(t, tt.expectToolchainChange, result.ToolchainChange != nil)
+			assert.Len(t, result.GodebugChanges, tt.expectedGodebug)
+			assert.Len(t, result.AddedTools, tt.expectedTools)
+
+			if tt.expectToolchainChange {
+				assert.Equal(t, "go1.24.2", targetMod.Toolchain.Name)
+			}
+		})
+	}
+}
+
+func TestFindMissingModules(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+require github.com/pkg/errors v0.9.1`)
+	require.NoError(t, err)
+
+	referenceMod, err := createTestModFile(`module example.com/reference
+
+require (
+	github.com/pkg/errors v0.9.2
+	golang.org/x/text v0.4.0
+	golang.org/x/sync v0.1.0 // indirect
+)`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ModuleChange{
+		{Module: "golang.org/x/text", Version: "v0.4.0", Indirect: true},
+		{Module: "golang.org/x/sync", Version: "v0.1.0", Indirect: true},
+	}, FindMissingModules(targetMod, referenceMod, true))
+
+	assert.Equal(t, []ModuleChange{
+		{Module: "golang.org/x/text", Version: "v0.4.0"},
+		{Module: "golang.org/x/sync", Version: "v0.1.0"},
+	}, FindMissingModules(targetMod, referenceMod, false))
+}
+
+func TestFindPrunableModules(t *testing.T) {
+	targetMod, err := createTestModFile(`module example.com/test
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/extra/dep v1.0.0
+	golang.org/x/sync v0.1.0 // indirect
+)`)
+	require.NoError(t, err)
+
+	refVersions := VersionMap{"github.com/pkg/errors": "v0.9.2"}
+
+	assert.Equal(t, []ModuleChange{
+		{Module: "github.com/extra/dep", Version: "v1.0.0"},
+		{Module: "golang.org/x/sync", Version: "v0.1.0", Indirect: true},
+	}, FindPrunableModules(targetMod, refVersions))
+}
+
+func TestSyncVersionsWithOptions_AddMissingAndPrune(t *testing.T) {
+	targetContent := `module example.com/test
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/extra/dep v1.0.0
+)`
+	referenceContent := `module example.com/reference
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.2
+	golang.org/x/text v0.4.0
+)`
+
+	tests := []struct {
+		name             string
+		opts             SyncOptions
+		expectedAdded    int
+		expectedRemoved  int
+		expectedVersions VersionMap
+	}{
+		{
+			name:            "neither",
+			opts:            SyncOptions{},
+			expectedAdded:   0,
+			expectedRemoved: 0,
+			expectedVersions: VersionMap{
+				"github.com/pkg/errors": "v0.9.2",
+				"github.com/extra/dep":  "v1.0.0",
+			},
+		},
+		{
+			name:            "add missing",
+			opts:            SyncOptions{AddMissing: true, AddMissingIndirect: true},
+			expectedAdded:   1,
+			expectedRemoved: 0,
+			expectedVersions: VersionMap{
+				"github.com/pkg/errors": "v0.9.2",
+				"github.com/extra/dep":  "v1.0.0",
+				"golang.org/x/text":     "v0.4.0",
+			},
+		},
+		{
+			name:            "prune",
+			opts:            SyncOptions{Prune: true},
+			expectedAdded:   0,
+			expectedRemoved: 1,
+			expectedVersions: VersionMap{
+				"github.com/pkg/errors": "v0.9.2",
+			},
+		},
+		{
+			name:            "add missing and prune",
+			opts:            SyncOptions{AddMissing: true, Prune: true},
+			expectedAdded:   1,
+			expectedRemoved: 1,
+			expectedVersions: VersionMap{
+				"github.com/pkg/errors": "v0.9.2",
+				"golang.org/x/text":     "v0.4.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetMod, err := createTestModFile(targetContent)
+			require.NoError(t, err)
+			referenceMod, err := createTestModFile(referenceContent)
+			require.NoError(t, err)
+
+			result, err := SyncVersionsWithOptions(targetMod, referenceMod, tt.opts)
+			require.NoError(t, err)
+
+			assert.Len(t, result.DependencyChanges, 1)
+			assert.Len(t, result.AddedModules, tt.expectedAdded)
+			assert.Len(t, result.RemovedModules, tt.expectedRemoved)
+			assert.Equal(t, 1+tt.expectedAdded+tt.expectedRemoved, result.TotalChanges())
+			assert.Equal(t, tt.expectedVersions, BuildVersionMap(targetMod))
+
+			// The strict check must pass once missing modules are added and extras pruned
+			if tt.opts.AddMissing && tt.opts.Prune {
+				check := CheckVersions(targetMod, referenceMod, true)
+				assert.Equal(t, 0, check.TotalMismatches())
+			}
+		})
+	}
+}
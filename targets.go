@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultSkipDirs lists the directory names that are not searched for go.mod files
+var defaultSkipDirs = []string{"testdata", "vendor"}
+
+// patternRoot converts a target pattern into the directory it names and
+// whether the pattern is recursive. As with the go command, only a pattern
+// ending in "..." such as "./..." or "services/..." covers the whole tree;
+// a plain directory such as "services" covers just that directory.
+func patternRoot(pattern string) (string, bool) {
+	root, recursive := strings.CutSuffix(pattern, "...")
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		return ".", recursive
+	}
+	return root, recursive
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// DiscoverGoModFiles walks the directory tree under root and returns the paths
+// of all go.mod files in lexical order. Directories named in skip are not entered,
+// and, like the go command's "./..." pattern, neither are directories whose name
+// starts with "." or "_".
+func DiscoverGoModFiles(root string, skip []string) ([]string, error) {
+	skipSet := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipSet[name] = true
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			name := d.Name()
+			if skipSet[name] || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover go.mod files under %s: %w", root, err)
+	}
+
+	return files, nil
+}
+
+// ResolveTargets returns the list of go.mod files to operate on. A single
+// target file takes precedence, and a go.work target expands to the go.mod
+// files of the modules in its use directives. Otherwise every comma-separated
+// pattern in targets is expanded: recursive patterns with DiscoverGoModFiles
+// and plain directories to their own go.mod.
+func ResolveTargets(target, targets string, skip []string) ([]string, error) {
+	if target != "" && IsWorkFile(target) {
+		return workspaceTargets(target)
+	}
+	if target != "" {
+		return []string{target}, nil
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range splitList(targets) {
+		root, recursive := patternRoot(pattern)
+		if _, err := os.Stat(root); err != nil {
+			return nil, fmt.Errorf("invalid target pattern %q: %w", pattern, err)
+		}
+
+		var found []string
+		if recursive {
+			var err error
+			if found, err = DiscoverGoModFiles(root, skip); err != nil {
+				return nil, err
+			}
+		} else if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			found = []string{filepath.Join(root, "go.mod")}
+		}
+		for _, file := range found {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no go.mod files found for %q", targets)
+	}
+	return files, nil
+}
+
+// workspaceTargets returns the go.mod files of the modules used by a go.work file
+func workspaceTargets(workPath string) ([]string, error) {
+	data, err := os.ReadFile(workPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.work file: %w", err)
+	}
+
+	work, err := ParseGoWork(workPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.work file: %w", err)
+	}
+
+	files, err := WorkspaceModFiles(workPath, work)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("go.work file %s has no use directives", workPath)
+	}
+	return files, nil
+}
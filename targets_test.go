@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestFile creates a file (and its parent directories) under dir
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestPatternRoot(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		expected          string
+		expectedRecursive bool
+	}{
+		{"recursive current dir", "./...", ".", true},
+		{"recursive subdir", "services/...", "services", true},
+		{"bare ellipsis", "...", ".", true},
+		{"plain dir", "services", "services", false},
+		{"trailing slash", "services/", "services", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, recursive := patternRoot(tt.input)
+			assert.Equal(t, tt.expected, root)
+			assert.Equal(t, tt.expectedRecursive, recursive)
+		})
+	}
+}
+
+func TestDiscoverGoModFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"go.mod",
+		"services/api/go.mod",
+		"services/worker/go.mod",
+		"services/api/testdata/go.mod",
+		"vendor/example.com/dep/go.mod",
+		".git/go.mod",
+		"_old/go.mod",
+		"tools/README.md",
+	} {
+		writeTestFile(t, root, name, "module example.com/test\n")
+	}
+
+	tests := []struct {
+		name     string
+		skip     []string
+		expected []string
+	}{
+		{
+			name:     "default skip list",
+			skip:     defaultSkipDirs,
+			expected: []string{"go.mod", "services/api/go.mod", "services/worker/go.mod"},
+		},
+		{
+			name:     "custom skip list",
+			skip:     []string{"worker"},
+			expected: []string{"go.mod", "services/api/go.mod", "services/api/testdata/go.mod", "vendor/example.com/dep/go.mod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := DiscoverGoModFiles(root, tt.skip)
+			require.NoError(t, err)
+
+			var relative []string
+			for _, file := range files {
+				rel, err := filepath.Rel(root, file)
+				require.NoError(t, err)
+				relative = append(relative, filepath.ToSlash(rel))
+			}
+			assert.Equal(t, tt.expected, relative)
+		})
+	}
+}
+
+func TestResolveTargets(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "a/go.mod", "module example.com/a\n")
+	writeTestFile(t, root, "b/go.mod", "module example.com/b\n")
+
+	t.Run("single target", func(t *testing.T) {
+		files, err := ResolveTargets("go.mod", "", defaultSkipDirs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"go.mod"}, files)
+	})
+
+	t.Run("pattern", func(t *testing.T) {
+		files, err := ResolveTargets("", root+"/...", defaultSkipDirs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(root, "a", "go.mod"), filepath.Join(root, "b", "go.mod")}, files)
+	})
+
+	t.Run("plain directory is not searched recursively", func(t *testing.T) {
+		files, err := ResolveTargets("", filepath.Join(root, "a"), defaultSkipDirs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(root, "a", "go.mod")}, files)
+
+		_, err = ResolveTargets("", root, defaultSkipDirs)
+		assert.Error(t, err)
+	})
+
+	t.Run("overlapping patterns are deduplicated", func(t *testing.T) {
+		files, err := ResolveTargets("", root+"/...,"+filepath.Join(root, "a")+"/...", defaultSkipDirs)
+		require.NoError(t, err)
+		assert.Len(t, files, 2)
+	})
+
+	t.Run("go.work target", func(t *testing.T) {
+		workPath := writeTestFile(t, root, "go.work", "go 1.22\n\nuse (\n\t./b\n\t./a\n)\n")
+		files, err := ResolveTargets(workPath, "", defaultSkipDirs)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(root, "b", "go.mod"), filepath.Join(root, "a", "go.mod")}, files)
+	})
+
+	t.Run("no go.mod files", func(t *testing.T) {
+		_, err := ResolveTargets("", t.TempDir()+"/...", defaultSkipDirs)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := ResolveTargets("", filepath.Join(root, "missing")+"/...", defaultSkipDirs)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TLSSettings configures HTTPS connections to servers with an internal CA or
+// mutual TLS. The zero value uses the system roots.
+type TLSSettings struct {
+	CAFile      string // PEM bundle of CAs trusted in addition to the system roots
+	ClientCert  string // PEM client certificate for mutual TLS
+	ClientKey   string // PEM private key of ClientCert
+	Fingerprint string // SHA-256 of the server certificate the server must present
+}
+
+// errFingerprintMismatch reports a server certificate other than the pinned one
+var errFingerprintMismatch = errors.New("server certificate does not match the pinned fingerprint")
+
+var (
+	httpClientsMu sync.Mutex
+	// httpClients reuses one client, and its connections, per TLS setup
+	httpClients = make(map[TLSSettings]*http.Client)
+)
+
+// httpClient returns the client for downloads with the given TLS settings
+func httpClient(settings TLSSettings) (*http.Client, error) {
+	if settings == (TLSSettings{}) {
+		return http.DefaultClient, nil
+	}
+
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	if client, exists := httpClients[settings]; exists {
+		return client, nil
+	}
+
+	config, err := tlsConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("default HTTP transport cannot be configured for TLS")
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = config
+	client := &http.Client{Transport: transport}
+	httpClients[settings] = client
+	return client, nil
+}
+
+// tlsConfig builds the TLS configuration of a client. A pinned fingerprint is
+// checked in addition to the usual certificate verification.
+func tlsConfig(settings TLSSettings) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if settings.CAFile != "" {
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", settings.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (settings.ClientCert == "") != (settings.ClientKey == "") {
+		return nil, errors.New("-client-cert and -client-key must be used together")
+	}
+	if settings.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(settings.ClientCert, settings.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if settings.Fingerprint != "" {
+		want, err := parseFingerprint(settings.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errFingerprintMismatch
+			}
+			got := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if subtle.ConstantTimeCompare(got[:], want) != 1 {
+				return errFingerprintMismatch
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
+
+// parseFingerprint decodes a SHA-256 fingerprint given in hex, optionally
+// with a sha256: prefix and colons between the bytes as openssl prints it
+func parseFingerprint(value string) ([]byte, error) {
+	hexValue := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "sha256:")
+	hexValue = strings.ReplaceAll(hexValue, ":", "")
+	fingerprint, err := hex.DecodeString(hexValue)
+	if err != nil || len(fingerprint) != sha256.Size {
+		return nil, fmt.Errorf("invalid certificate fingerprint %q: expected a hex SHA-256 hash", value)
+	}
+	return fingerprint, nil
+}
+
+// isTLSFailure checks if a download failed verifying the server certificate,
+// which retrying cannot fix
+func isTLSFailure(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.Is(err, errFingerprintMismatch)
+}
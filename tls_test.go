@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSTestServer starts an HTTPS server serving a go.mod and writes its
+// certificate to a PEM file usable as -ca-file
+func newTLSTestServer(t *testing.T, clientCAs *x509.CertPool) (*httptest.Server, string) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("module example.com/test\n"))
+	}))
+	if clientCAs != nil {
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile := writeTestFile(t, t.TempDir(), "ca.pem",
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+	return server, caFile
+}
+
+// newTestClientCert creates a self-signed client certificate and returns it
+// with the paths of its PEM certificate and key files
+func newTestClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gomodsync-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := writeTestFile(t, dir, "client.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	keyFile := writeTestFile(t, dir, "client-key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})))
+	return cert, certFile, keyFile
+}
+
+func TestFetchFromURL_CAFile(t *testing.T) {
+	clearAuthEnv(t)
+	server, caFile := newTLSTestServer(t, nil)
+
+	// The test server's certificate is not trusted by default, and that is not retried
+	_, err := fetchFromURL(server.URL)
+	require.Error(t, err)
+	assert.True(t, isTLSFailure(err))
+	assert.False(t, isRetryable(fetchSettings.Context, err))
+
+	withFetchSettings(t, func(s *FetchSettings) { s.TLS.CAFile = caFile })
+	data, err := fetchFromURL(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "module example.com/test\n", string(data))
+}
+
+func TestFetchFromURL_ClientCert(t *testing.T) {
+	clearAuthEnv(t)
+	cert, certFile, keyFile := newTestClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+	server, caFile := newTLSTestServer(t, clientCAs)
+
+	withFetchSettings(t, func(s *FetchSettings) {
+		s.Retries = 0
+		s.TLS.CAFile = caFile
+	})
+	_, err := fetchFromURL(server.URL)
+	require.Error(t, err)
+
+	withFetchSettings(t, func(s *FetchSettings) {
+		s.TLS.ClientCert = certFile
+		s.TLS.ClientKey = keyFile
+	})
+	_, err = fetchFromURL(server.URL)
+	require.NoError(t, err)
+}
+
+func TestFetchFromURL_Fingerprint(t *testing.T) {
+	clearAuthEnv(t)
+	server, caFile := newTLSTestServer(t, nil)
+	sum := sha256.Sum256(server.Certificate().Raw)
+
+	withFetchSettings(t, func(s *FetchSettings) {
+		s.TLS.CAFile = caFile
+		s.TLS.Fingerprint = strings.ToUpper(hex.EncodeToString(sum[:]))
+	})
+	_, err := fetchFromURL(server.URL)
+	require.NoError(t, err)
+
+	withFetchSettings(t, func(s *FetchSettings) { s.TLS.Fingerprint = strings.Repeat("ab", sha256.Size) })
+	_, err = fetchFromURL(server.URL)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errFingerprintMismatch)
+}
+
+func TestParseFingerprint(t *testing.T) {
+	hexValue := strings.Repeat("0a", sha256.Size)
+	colons := strings.TrimSuffix(strings.Repeat("0A:", sha256.Size), ":")
+
+	tests := []struct {
+		input     string
+		expectErr bool
+	}{
+		{hexValue, false},
+		{"sha256:" + hexValue, false},
+		{"SHA256:" + colons, false},
+		{hexValue[:10], true},
+		{"not hex", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			fingerprint, err := parseFingerprint(tt.input)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, fingerprint, sha256.Size)
+		})
+	}
+}
+
+func TestTLSConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := writeTestFile(t, dir, "ca.pem", "not a certificate")
+
+	tests := []struct {
+		name     string
+		settings TLSSettings
+	}{
+		{"missing CA file", TLSSettings{CAFile: filepath.Join(dir, "missing.pem")}},
+		{"CA file without certificates", TLSSettings{CAFile: notPEM}},
+		{"client certificate without key", TLSSettings{ClientCert: notPEM}},
+		{"invalid client certificate", TLSSettings{ClientCert: notPEM, ClientKey: notPEM}},
+		{"invalid fingerprint", TLSSettings{Fingerprint: "abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tlsConfig(tt.settings)
+			assert.Error(t, err)
+		})
+	}
+}
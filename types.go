@@ -1,43 +1,227 @@
 package main
 
+import "golang.org/x/mod/modfile"
+
 // VersionChange represents a single version update
 type VersionChange struct {
-	Module     string
-	OldVersion string
-	NewVersion string
+	Module     string `json:"module"`
+	OldVersion string `json:"oldVersion"`
+	NewVersion string `json:"newVersion"`
+	Source     string `json:"source,omitempty"` // reference layer NewVersion came from
+}
+
+// ReplaceChange represents a replace directive that sync adds, updates or removes
+type ReplaceChange struct {
+	Module                string `json:"module"`         // replaced module path
+	ModuleVersion         string `json:"moduleVersion"`  // replaced module version, empty if all versions are replaced
+	OldReplacement        string `json:"oldReplacement"` // empty if the replace directive is added
+	OldReplacementVersion string `json:"oldReplacementVersion"`
+	NewReplacement        string `json:"newReplacement"` // empty if the replace directive is removed
+	NewReplacementVersion string `json:"newReplacementVersion"`
+	Source                string `json:"source,omitempty"` // reference layer NewReplacement came from
+}
+
+// ExcludeChange represents an exclude directive that sync adds to the target
+type ExcludeChange struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Source  string `json:"source,omitempty"` // reference layer the exclude came from
+}
+
+// GodebugChange represents a godebug setting that sync adds or updates.
+// OldValue is empty if the setting is added.
+type GodebugChange struct {
+	Key      string `json:"key"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+	Source   string `json:"source,omitempty"` // reference layer NewValue came from
+}
+
+// ModuleChange represents a requirement that sync adds to or removes from the target
+type ModuleChange struct {
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Indirect bool   `json:"indirect"`
+	Source   string `json:"source,omitempty"` // reference layer Version came from
 }
 
 // SyncResult contains the results of a sync operation
 type SyncResult struct {
-	DependencyChanges []VersionChange
-	GoVersionChange   *GoVersionChange
+	DependencyChanges []VersionChange  `json:"dependencyChanges"`
+	AddedModules      []ModuleChange   `json:"addedModules"`
+	RemovedModules    []ModuleChange   `json:"removedModules"`
+	GoVersionChange   *GoVersionChange `json:"goVersionChange"`
+	ToolchainChange   *ToolchainChange `json:"toolchainChange"`
+	GodebugChanges    []GodebugChange  `json:"godebugChanges"`
+	AddedTools        []string         `json:"addedTools"`
+	ReplaceChanges    []ReplaceChange  `json:"replaceChanges"`
+	ExcludeChanges    []ExcludeChange  `json:"excludeChanges"`
 }
 
 // GoVersionChange represents a Go version update
 type GoVersionChange struct {
-	OldVersion string
-	NewVersion string
+	OldVersion string `json:"oldVersion"`
+	NewVersion string `json:"newVersion"`
+	Source     string `json:"source,omitempty"` // reference layer NewVersion came from
+}
+
+// ToolchainChange represents a toolchain directive update
+type ToolchainChange struct {
+	OldVersion string `json:"oldVersion"`
+	NewVersion string `json:"newVersion"`
+	Source     string `json:"source,omitempty"` // reference layer NewVersion came from
 }
 
 // VersionMismatch represents a version difference in check mode
 type VersionMismatch struct {
-	Module           string
-	TargetVersion    string
-	ReferenceVersion string
-	OnlyInTarget     bool // true if module exists only in target
+	Module           string `json:"module"`
+	TargetVersion    string `json:"targetVersion"`
+	ReferenceVersion string `json:"referenceVersion"`
+	OnlyInTarget     bool   `json:"onlyInTarget"`         // true if module exists only in target
+	OnlyInReference  bool   `json:"onlyInReference"`      // true if module exists only in reference
+	Source           string `json:"source,omitempty"`     // reference layer ReferenceVersion came from
+	Constraint       string `json:"constraint,omitempty"` // policy file constraint the target version violates
+}
+
+// ReplaceMismatch represents a replace directive difference in check mode.
+// An empty replacement means the directive is missing on that side.
+type ReplaceMismatch struct {
+	Module                      string `json:"module"`
+	ModuleVersion               string `json:"moduleVersion"`
+	TargetReplacement           string `json:"targetReplacement"`
+	TargetReplacementVersion    string `json:"targetReplacementVersion"`
+	ReferenceReplacement        string `json:"referenceReplacement"`
+	ReferenceReplacementVersion string `json:"referenceReplacementVersion"`
+	Source                      string `json:"source,omitempty"` // reference layer ReferenceReplacement came from
+}
+
+// ExcludedRequirement represents a target requirement on a version the reference excludes
+type ExcludedRequirement struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Source  string `json:"source,omitempty"` // reference layer that excludes Version
+}
+
+// GodebugMismatch represents a godebug setting difference in check mode.
+// TargetValue is empty if the target does not set the key.
+type GodebugMismatch struct {
+	Key            string `json:"key"`
+	TargetValue    string `json:"targetValue"`
+	ReferenceValue string `json:"referenceValue"`
+	Source         string `json:"source,omitempty"` // reference layer ReferenceValue came from
 }
 
 // CheckResult contains the results of a check operation
 type CheckResult struct {
-	DependencyMismatches []VersionMismatch
-	GoVersionMismatch    *GoVersionMismatch
+	DependencyMismatches []VersionMismatch     `json:"dependencyMismatches"`
+	GoVersionMismatch    *GoVersionMismatch    `json:"goVersionMismatch"`
+	ToolchainMismatch    *ToolchainMismatch    `json:"toolchainMismatch"`
+	GodebugMismatches    []GodebugMismatch     `json:"godebugMismatches"`
+	MissingTools         []string              `json:"missingTools"` // reference tools the target does not declare
+	ReplaceMismatches    []ReplaceMismatch     `json:"replaceMismatches"`
+	ExcludedRequirements []ExcludedRequirement `json:"excludedRequirements"`
 }
 
 // GoVersionMismatch represents a Go version difference
 type GoVersionMismatch struct {
-	TargetVersion    string
-	ReferenceVersion string
+	TargetVersion    string `json:"targetVersion"`
+	ReferenceVersion string `json:"referenceVersion"`
+	Source           string `json:"source,omitempty"` // reference layer ReferenceVersion came from
+}
+
+// ToolchainMismatch represents a toolchain directive difference
+type ToolchainMismatch struct {
+	TargetVersion    string `json:"targetVersion"`
+	ReferenceVersion string `json:"referenceVersion"`
+	Source           string `json:"source,omitempty"` // reference layer ReferenceVersion came from
 }
 
 // VersionMap is a map of module paths to their versions
 type VersionMap map[string]string
+
+// ReplaceMap is a map of replaced modules (path or path@version) to their replace directives
+type ReplaceMap map[string]*modfile.Replace
+
+// LocalReplacePolicy controls how replace directives pointing at local directories are handled
+type LocalReplacePolicy string
+
+const (
+	// LocalReplacesKeep leaves local replaces in the target untouched and never copies them from the reference
+	LocalReplacesKeep LocalReplacePolicy = "keep"
+	// LocalReplacesSync treats local replaces like any other replace directive
+	LocalReplacesSync LocalReplacePolicy = "sync"
+)
+
+// Directive names a single-valued go.mod directive that sync and check can cover
+type Directive string
+
+// Directives that can be selected with -directives
+const (
+	DirectiveGo        Directive = "go"
+	DirectiveToolchain Directive = "toolchain"
+	DirectiveGodebug   Directive = "godebug"
+	DirectiveTool      Directive = "tool"
+)
+
+// DirectiveSet is the set of directives taking part in a sync or check
+type DirectiveSet map[Directive]bool
+
+// SyncOptions controls which go.mod directives SyncVersionsWithOptions updates
+type SyncOptions struct {
+	Policy             VersionPolicy
+	GoPolicy           GoVersionPolicy
+	GoMax              string // highest go version sync may set, empty for no cap
+	AddMissing         bool   // add reference requirements the target lacks
+	AddMissingIndirect bool   // mark added requirements as // indirect
+	Prune              bool   // remove target requirements absent from the reference
+	Directives         DirectiveSet
+	Replaces           bool // also sync replace directives
+	LocalReplaces      LocalReplacePolicy
+	Excludes           bool              // also copy exclude directives from the reference
+	Sources            *ReferenceSources // reference layers of each version, nil for a single reference
+	Constraints        *ConstraintSet    // policy file constraints that override the reference per module
+	Latest             *LatestResolver   // builds each target's reference from the module proxy instead
+}
+
+// CheckOptions controls which go.mod directives CheckVersionsWithOptions compares
+type CheckOptions struct {
+	Strict        bool // also report dependencies that exist only in target
+	ReverseStrict bool // also report dependencies that exist only in reference
+	Policy        VersionPolicy
+	GoPolicy      GoVersionPolicy
+	Directives    DirectiveSet
+	Replaces      bool // also compare replace directives
+	LocalReplaces LocalReplacePolicy
+	Excludes      bool              // also report requirements on versions the reference excludes
+	Sources       *ReferenceSources // reference layers of each version, nil for a single reference
+	Constraints   *ConstraintSet    // policy file constraints that override the reference per module
+	Latest        *LatestResolver   // builds each target's reference from the module proxy instead
+}
+
+// TotalChanges returns the number of changes in the sync result,
+// counting the Go version and toolchain changes as one each
+func (r *SyncResult) TotalChanges() int {
+	total := len(r.DependencyChanges) + len(r.AddedModules) + len(r.RemovedModules) +
+		len(r.GodebugChanges) + len(r.AddedTools) + len(r.ReplaceChanges) + len(r.ExcludeChanges)
+	if r.GoVersionChange != nil {
+		total++
+	}
+	if r.ToolchainChange != nil {
+		total++
+	}
+	return total
+}
+
+// TotalMismatches returns the number of mismatches in the check result,
+// counting the Go version and toolchain mismatches as one each
+func (r *CheckResult) TotalMismatches() int {
+	total := len(r.DependencyMismatches) + len(r.GodebugMismatches) + len(r.MissingTools) +
+		len(r.ReplaceMismatches) + len(r.ExcludedRequirements)
+	if r.GoVersionMismatch != nil {
+		total++
+	}
+	if r.ToolchainMismatch != nil {
+		total++
+	}
+	return total
+}
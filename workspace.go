@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	gover "go/version"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// IsWorkFile checks if the given path or URL names a go.work file
+func IsWorkFile(reference string) bool {
+	return path.Base(filepath.ToSlash(reference)) == "go.work" || strings.HasSuffix(reference, ".work")
+}
+
+// ParseGoWork reads and parses a go.work file
+func ParseGoWork(filename string, data []byte) (*modfile.WorkFile, error) {
+	return modfile.ParseWork(filename, data, nil)
+}
+
+// resolveWorkspacePath resolves the go.mod location of a use directive
+// relative to the go.work file, which may be a local path, a URL or a git
+// reference. In a git reference, the go.mod is read at the same revision.
+func resolveWorkspacePath(workPath, useDir string) (string, error) {
+	if isGitReference(workPath) {
+		return resolveGitWorkspacePath(workPath, useDir)
+	}
+	if isURL(workPath) {
+		base, err := url.Parse(workPath)
+		if err != nil {
+			return "", fmt.Errorf("invalid go.work URL: %w", err)
+		}
+		rel, err := url.Parse(path.Join(filepath.ToSlash(useDir), "go.mod"))
+		if err != nil {
+			return "", fmt.Errorf("invalid use directive %q: %w", useDir, err)
+		}
+		return base.ResolveReference(rel).String(), nil
+	}
+
+	if filepath.IsAbs(useDir) {
+		return filepath.Join(useDir, "go.mod"), nil
+	}
+	return filepath.Join(filepath.Dir(workPath), useDir, "go.mod"), nil
+}
+
+// resolveGitWorkspacePath resolves a use directive of a go.work file read
+// from a git reference to the module's go.mod at the same repository and ref
+func resolveGitWorkspacePath(workPath, useDir string) (string, error) {
+	parsed, err := parseGitReference(workPath)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.ToSlash(useDir)
+	if path.IsAbs(dir) || filepath.IsAbs(useDir) {
+		return "", fmt.Errorf("use directive %q: an absolute directory cannot be read from a git reference", useDir)
+	}
+	file := path.Join(path.Dir(parsed.path), dir, "go.mod")
+	if file == ".." || strings.HasPrefix(file, "../") {
+		return "", fmt.Errorf("use directive %q: directory is outside the repository", useDir)
+	}
+	parsed.path = file
+	return parsed.String(), nil
+}
+
+// WorkspaceModFiles returns the go.mod locations of the modules listed
+// in the use directives of a go.work file, in declaration order
+func WorkspaceModFiles(workPath string, work *modfile.WorkFile) ([]string, error) {
+	files := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		file, err := resolveWorkspacePath(workPath, use.Path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// BuildWorkspaceReference merges the go.mod files of a workspace into a single
+// reference modfile. Requirements shared by several modules resolve to the highest
+// version, as minimal version selection would, and requirements on modules that
+// are part of the workspace are dropped. Replacements from the go.work file take
+// precedence over those of the individual modules. The go, toolchain and godebug
+// directives come from the go.work file; if it has no go directive, the highest
+// go version of the modules is used.
+func BuildWorkspaceReference(filename string, work *modfile.WorkFile, mods []*modfile.File) (*modfile.File, error) {
+	reference, err := ParseGoMod(filename, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceModules := make(map[string]bool, len(mods))
+	for _, mod := range mods {
+		if mod.Module != nil {
+			workspaceModules[mod.Module.Mod.Path] = true
+		}
+	}
+
+	versions := make(VersionMap)
+	indirect := make(map[string]bool)
+	replaces := make(ReplaceMap)
+	var goVersion string
+
+	for _, mod := range mods {
+		if mod.Go != nil && gover.Compare("go"+mod.Go.Version, "go"+goVersion) > 0 {
+			goVersion = mod.Go.Version
+		}
+		mergeWorkspaceRequires(versions, indirect, mod, workspaceModules)
+		for _, rep := range mod.Replace {
+			replaces[rep.Old.String()] = rep
+		}
+		for _, exc := range mod.Exclude {
+			if err := reference.AddExclude(exc.Mod.Path, exc.Mod.Version); err != nil {
+				return nil, fmt.Errorf("failed to add exclude %s: %w", exc.Mod, err)
+			}
+		}
+	}
+
+	for _, rep := range work.Replace {
+		replaces[rep.Old.String()] = rep
+	}
+
+	if work.Go != nil {
+		goVersion = work.Go.Version
+	}
+	if err := addWorkspaceDirectives(reference, work, goVersion); err != nil {
+		return nil, err
+	}
+
+	addRequires(reference, versions, indirect)
+	if err := addReplaces(reference, replaces); err != nil {
+		return nil, err
+	}
+	return reference, nil
+}
+
+// mergeWorkspaceRequires adds the requirements of a workspace module to
+// versions, keeping the highest version of each. A requirement is indirect only
+// if every module requires it indirectly. Modules of the workspace are skipped.
+func mergeWorkspaceRequires(versions VersionMap, indirect map[string]bool, mod *modfile.File, workspaceModules map[string]bool) {
+	for _, req := range mod.Require {
+		if workspaceModules[req.Mod.Path] {
+			continue
+		}
+		current, exists := versions[req.Mod.Path]
+		if !exists || semver.Compare(req.Mod.Version, current) > 0 {
+			versions[req.Mod.Path] = req.Mod.Version
+		}
+		if !exists {
+			indirect[req.Mod.Path] = req.Indirect
+		} else if !req.Indirect {
+			indirect[req.Mod.Path] = false
+		}
+	}
+}
+
+// addWorkspaceDirectives sets the go, toolchain and godebug directives of a
+// workspace reference
+func addWorkspaceDirectives(reference *modfile.File, work *modfile.WorkFile, goVersion string) error {
+	if goVersion != "" {
+		if err := reference.AddGoStmt(goVersion); err != nil {
+			return fmt.Errorf("failed to set Go version: %w", err)
+		}
+	}
+	if work.Toolchain != nil {
+		if err := reference.AddToolchainStmt(work.Toolchain.Name); err != nil {
+			return fmt.Errorf("failed to set toolchain: %w", err)
+		}
+	}
+	for _, godebug := range work.Godebug {
+		if err := reference.AddGodebug(godebug.Key, godebug.Value); err != nil {
+			return fmt.Errorf("failed to add godebug %s: %w", godebug.Key, err)
+		}
+	}
+	return nil
+}
+
+// loadWorkspaceReference fetches a go.work file (from URL or local path)
+// together with the go.mod files of its modules and merges them into
+// a single reference modfile
+func loadWorkspaceReference(reference string) (*modfile.File, error) {
+	workData, err := FetchReference(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reference: %w", err)
+	}
+
+	referenceName := GetReferenceDisplayName(reference)
+	work, err := ParseGoWork(referenceName, workData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	modFiles, err := WorkspaceModFiles(reference, work)
+	if err != nil {
+		return nil, err
+	}
+
+	mods := make([]*modfile.File, 0, len(modFiles))
+	for _, modFile := range modFiles {
+		data, err := FetchReference(modFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch workspace module: %w", err)
+		}
+		mod, err := ParseGoMod(GetReferenceDisplayName(modFile), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse workspace module: %w", err)
+		}
+		mods = append(mods, mod)
+	}
+
+	return BuildWorkspaceReference(referenceName, work, mods)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+func TestIsWorkFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"go.work file", "./go.work", true},
+		{"nested go.work", "/repo/go.work", true},
+		{"custom work file", "platform.work", true},
+		{"go.work URL", "https://example.com/repo/go.work", true},
+		{"go.mod file", "./go.mod", false},
+		{"go.mod URL", "https://example.com/go.mod", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsWorkFile(tt.input))
+		})
+	}
+}
+
+func TestWorkspaceModFiles(t *testing.T) {
+	work, err := ParseGoWork("go.work", []byte(`go 1.22
+
+use (
+	./api
+	./tools/gen
+)`))
+	require.NoError(t, err)
+
+	t.Run("local path", func(t *testing.T) {
+		files, err := WorkspaceModFiles(filepath.Join("repo", "go.work"), work)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join("repo", "api", "go.mod"),
+			filepath.Join("repo", "tools", "gen", "go.mod"),
+		}, files)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		files, err := WorkspaceModFiles("https://example.com/repo/go.work", work)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"https://example.com/repo/api/go.mod",
+			"https://example.com/repo/tools/gen/go.mod",
+		}, files)
+	})
+
+	t.Run("git reference", func(t *testing.T) {
+		files, err := WorkspaceModFiles("git+https://example.com/org/repo.git@v1.0.0:services/go.work", work)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"git+https://example.com/org/repo.git@v1.0.0:services/api/go.mod",
+			"git+https://example.com/org/repo.git@v1.0.0:services/tools/gen/go.mod",
+		}, files)
+	})
+
+	t.Run("git reference outside the repository", func(t *testing.T) {
+		outside, err := ParseGoWork("go.work", []byte("use ../other\n"))
+		require.NoError(t, err)
+		_, err = WorkspaceModFiles("git:../platform@v1.0.0:go.work", outside)
+		assert.Error(t, err)
+
+		absolute, err := ParseGoWork("go.work", []byte("use /abs/module\n"))
+		require.NoError(t, err)
+		_, err = WorkspaceModFiles("git:../platform@v1.0.0:go.work", absolute)
+		assert.Error(t, err)
+	})
+}
+
+func TestBuildWorkspaceReference(t *testing.T) {
+	work, err := ParseGoWork("go.work", []byte(`go 1.23
+
+toolchain go1.23.4
+
+use (
+	./api
+	./worker
+)
+
+replace github.com/pkg/errors => github.com/fork/errors v0.9.3
+`))
+	require.NoError(t, err)
+
+	api, err := createTestModFile(`module example.com/api
+
+go 1.22
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.3.0 // indirect
+	example.com/worker v0.0.0
+)
+
+replace github.com/pkg/errors => github.com/other/errors v0.9.2
+`)
+	require.NoError(t, err)
+
+	worker, err := createTestModFile(`module example.com/worker
+
+go 1.21
+
+require (
+	golang.org/x/text v0.4.0
+	golang.org/x/sync v0.1.0 // indirect
+)`)
+	require.NoError(t, err)
+
+	reference, err := BuildWorkspaceReference("go.work", work, []*modfile.File{api, worker})
+	require.NoError(t, err)
+
+	assert.Equal(t, VersionMap{
+		"github.com/pkg/errors": "v0.9.1",
+		"golang.org/x/text":     "v0.4.0",
+		"golang.org/x/sync":     "v0.1.0",
+	}, BuildVersionMap(reference))
+
+	indirect := make(map[string]bool)
+	for _, req := range reference.Require {
+		indirect[req.Mod.Path] = req.Indirect
+	}
+	assert.False(t, indirect["golang.org/x/text"], "direct in one module should win")
+	assert.True(t, indirect["golang.org/x/sync"])
+
+	require.NotNil(t, reference.Go)
+	assert.Equal(t, "1.23", reference.Go.Version)
+	require.NotNil(t, reference.Toolchain)
+	assert.Equal(t, "go1.23.4", reference.Toolchain.Name)
+
+	require.Len(t, reference.Replace, 1)
+	assert.Equal(t, "github.com/fork/errors", reference.Replace[0].New.Path)
+	assert.Equal(t, "v0.9.3", reference.Replace[0].New.Version)
+}
+
+func TestBuildWorkspaceReference_GoVersionFromModules(t *testing.T) {
+	work, err := ParseGoWork("go.work", []byte("use ./a\nuse ./b\n"))
+	require.NoError(t, err)
+
+	a, err := createTestModFile("module example.com/a\n\ngo 1.21.5\n")
+	require.NoError(t, err)
+	b, err := createTestModFile("module example.com/b\n\ngo 1.21\n")
+	require.NoError(t, err)
+
+	reference, err := BuildWorkspaceReference("go.work", work, []*modfile.File{a, b})
+	require.NoError(t, err)
+	require.NotNil(t, reference.Go)
+	assert.Equal(t, "1.21.5", reference.Go.Version)
+}
+
+func TestLoadWorkspaceReference(t *testing.T) {
+	files := map[string]string{
+		"/repo/go.work":    "go 1.22\n\nuse ./svc\n",
+		"/repo/svc/go.mod": "module example.com/svc\n\ngo 1.22\n\nrequire github.com/pkg/errors v0.9.2\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	t.Run("URL", func(t *testing.T) {
+		reference, err := loadWorkspaceReference(server.URL + "/repo/go.work")
+		require.NoError(t, err)
+		assert.Equal(t, VersionMap{"github.com/pkg/errors": "v0.9.2"}, BuildVersionMap(reference))
+	})
+
+	t.Run("local path", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range files {
+			writeTestFile(t, dir, name, content)
+		}
+		reference, err := loadWorkspaceReference(filepath.Join(dir, "repo", "go.work"))
+		require.NoError(t, err)
+		assert.Equal(t, VersionMap{"github.com/pkg/errors": "v0.9.2"}, BuildVersionMap(reference))
+	})
+
+	t.Run("git reference", func(t *testing.T) {
+		repo := t.TempDir()
+		git := testGit(t, repo)
+		git("init", "--quiet")
+		for name, content := range files {
+			writeTestFile(t, repo, name, content)
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", "workspace")
+		git("tag", "v1.0.0")
+
+		// The use directory is read at the revision, not from the working directory
+		cwd := t.TempDir()
+		writeTestFile(t, cwd, "repo/svc/go.mod", "module example.com/svc\n\nrequire github.com/pkg/errors v0.8.0\n")
+		t.Chdir(cwd)
+
+		reference, err := loadReference("git:" + repo + "@v1.0.0:repo/go.work")
+		require.NoError(t, err)
+		assert.Equal(t, VersionMap{"github.com/pkg/errors": "v0.9.2"}, BuildVersionMap(reference))
+	})
+
+	t.Run("missing module", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "go.work", "use ./missing\n")
+		_, err := loadWorkspaceReference(filepath.Join(dir, "go.work"))
+		assert.Error(t, err)
+	})
+}